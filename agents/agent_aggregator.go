@@ -0,0 +1,324 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/canercidam/large-tx-detector/clients"
+	"github.com/canercidam/large-tx-detector/contracts"
+	"github.com/canercidam/large-tx-detector/core/agent"
+	"github.com/canercidam/large-tx-detector/format"
+	"github.com/canercidam/large-tx-detector/labels"
+	"github.com/canercidam/large-tx-detector/rules"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Config vars
+var (
+	MaxWindowTransfers = 256
+)
+
+// Alert types
+const (
+	AlertTypeSplitTransfer = "split-transfer"
+)
+
+// Aggregation groups
+const (
+	GroupBySender   = "sender"
+	GroupByReceiver = "receiver"
+	GroupByPair     = "pair"
+)
+
+// Aggregation metadata keys
+const (
+	MetadataTransfers   = "transfers"
+	MetadataWindowStart = "windowStart"
+)
+
+// WindowTransfer is a transfer in an aggregation window.
+type WindowTransfer struct {
+	TxHash      string `json:"txHash"`
+	LogIndex    uint   `json:"logIndex"`
+	BlockNumber uint64 `json:"blockNumber"`
+	Timestamp   uint64 `json:"timestamp"`
+	Value       string `json:"value"`
+	// Count is the number of the merged transfers. Zero means a single transfer.
+	Count int `json:"count,omitempty"`
+}
+
+func (transfer *WindowTransfer) count() int {
+	if transfer.Count == 0 {
+		return 1
+	}
+	return transfer.Count
+}
+
+// TransferWindow is the recent transfers of a sender, a receiver or a pair.
+type TransferWindow struct {
+	Key       string            `json:"key"`
+	Transfers []*WindowTransfer `json:"transfers"`
+}
+
+// WindowRepository persists the aggregation windows.
+type WindowRepository interface {
+	SaveTransferWindow(agentID string, window *TransferWindow, ttl time.Duration) error
+	GetTransferWindow(agentID, key string) (*TransferWindow, error)
+}
+
+// AggregatorConfig contains the transfer aggregator agent config parameters. The window
+// is either in blocks or in seconds.
+type AggregatorConfig struct {
+	AgentID       string
+	ChainID       uint64
+	TokenAddress  string
+	Symbol        string
+	Decimals      int
+	GroupBy       string
+	WindowBlocks  uint64
+	WindowSeconds uint64
+	// Threshold is compared with the total volume in the window in token units.
+	Threshold uint64
+	Notifier  agent.Notifier
+	// Templates are used by the default notifier.
+	Templates *format.Templates
+	Client    *clients.RPC
+	Windows   WindowRepository
+	Tokens    TokenRepository
+	Labels    *labels.Registry
+	Ignore    *IgnoreList
+	Rules     *rules.Engine
+	Actions   agent.ActionRepository
+}
+
+// TransferAggregator sums the transfers in a sliding window to detect the large
+// transfers which were split into smaller ones. It implements the agent.Agent interface.
+type TransferAggregator struct {
+	config       *AggregatorConfig
+	tokenAddress common.Address
+	symbol       string
+	decimals     int
+	threshold    *big.Int
+	pipeline     *findingPipeline
+	contract     *bind.BoundContract
+
+	currentBlock    uint64
+	currentReceipts []*types.Receipt
+	currentState    int
+}
+
+// NewTransferAggregator creates a new transfer aggregator.
+func NewTransferAggregator(conf *AggregatorConfig) (*TransferAggregator, error) {
+	switch conf.GroupBy {
+	case GroupBySender, GroupByReceiver, GroupByPair:
+	default:
+		return nil, fmt.Errorf("invalid aggregation group '%s'", conf.GroupBy)
+	}
+	if (conf.WindowBlocks == 0) == (conf.WindowSeconds == 0) {
+		return nil, fmt.Errorf("either the window blocks or the window seconds must be specified")
+	}
+	ta := &TransferAggregator{config: conf}
+	ta.tokenAddress = common.HexToAddress(conf.TokenAddress)
+	ta.contract, _ = contracts.BindIERC20(ta.tokenAddress, nil, nil, nil)
+	ta.pipeline = newFindingPipeline(conf.AgentID, conf.Notifier, conf.Templates, conf.Labels, conf.Ignore, conf.Rules, conf.Actions)
+	return ta, nil
+}
+
+// Skip checks the logs bloom filter to see if we should skip this block entirely.
+func (ta *TransferAggregator) Skip(block *types.Block, tx *types.Transaction) bool {
+	hasTokenAddress := block.Bloom().Test(ta.tokenAddress.Bytes())
+	hasTopic := block.Bloom().Test(transferTopicHash.Bytes())
+	return !(hasTokenAddress && hasTopic)
+}
+
+// ID returns the agent ID.
+func (ta *TransferAggregator) ID() string {
+	return ta.config.AgentID
+}
+
+// Init inits the tx handling.
+func (ta *TransferAggregator) Init(op *agent.Operation, tx *types.Transaction) {
+	ta.currentState = op.State
+}
+
+// Next tells if we have a next state to continue handling.
+func (ta *TransferAggregator) Next() bool {
+	ta.currentState++
+	return ta.currentState < 2
+}
+
+// HandleTransaction adds the token transfers of the transaction to the windows.
+func (ta *TransferAggregator) HandleTransaction(ctx context.Context, block *types.Block, tx *types.Transaction) error {
+	if ta.threshold == nil {
+		token, err := resolveTokenMetadata(ctx, ta.config.Client, ta.config.Tokens, ta.tokenAddress, ta.config.Symbol, ta.config.Decimals)
+		if err != nil {
+			return err
+		}
+		ta.symbol = token.Symbol
+		ta.decimals = token.Decimals
+		exp := big.NewInt(0).Exp(big.NewInt(10), big.NewInt(int64(ta.decimals)), nil)
+		ta.threshold = big.NewInt(0).Mul(big.NewInt(0).SetUint64(ta.config.Threshold), exp)
+	}
+	if ta.currentBlock != block.NumberU64() {
+		receipts, err := getBlockReceipts(ctx, ta.config.Client, block)
+		if err != nil {
+			return err
+		}
+		ta.currentReceipts = receipts
+		ta.currentBlock = block.NumberU64()
+	}
+
+	for _, transferLog := range findLogs(ta.currentReceipts, tx, ta.tokenAddress, transferTopicHash) {
+		event, err := contracts.UnpackIERC20Transfer(ta.contract, transferLog)
+		if err != nil {
+			return fmt.Errorf("failed to unpack the event: %v", err)
+		}
+		if err := ta.aggregate(ctx, block, tx, transferLog, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// aggregate adds the transfer to its window and notifies if the total reaches the threshold.
+// The window is reset after notifying.
+func (ta *TransferAggregator) aggregate(ctx context.Context, block *types.Block, tx *types.Transaction, transferLog *types.Log, event *contracts.IERC20Transfer) error {
+	key, ok := ta.windowKey(event)
+	if !ok {
+		return nil
+	}
+	window, err := ta.config.Windows.GetTransferWindow(ta.config.AgentID, key)
+	if err != nil {
+		return fmt.Errorf("failed to get the transfer window: %v", err)
+	}
+	if window == nil {
+		window = &TransferWindow{Key: key}
+	}
+
+	// Drop the expired transfers and add the new one unless it was added before.
+	var (
+		transfers []*WindowTransfer
+		total     = big.NewInt(0)
+		txHash    = transferLog.TxHash.Hex()
+		count     int
+	)
+	for _, transfer := range window.Transfers {
+		if transfer.TxHash == txHash && transfer.LogIndex == transferLog.Index {
+			return nil
+		}
+		if ta.expired(transfer, block) {
+			continue
+		}
+		value, ok := big.NewInt(0).SetString(transfer.Value, 10)
+		if !ok {
+			continue
+		}
+		total.Add(total, value)
+		count += transfer.count()
+		transfers = append(transfers, transfer)
+	}
+	transfers = append(transfers, &WindowTransfer{
+		TxHash:      txHash,
+		LogIndex:    transferLog.Index,
+		BlockNumber: block.NumberU64(),
+		Timestamp:   block.Time(),
+		Value:       event.Value.String(),
+	})
+	total.Add(total, event.Value)
+	count++
+	window.Transfers = mergeOldestTransfers(transfers, MaxWindowTransfers)
+
+	// A single large transfer is the job of the large tx detector.
+	if count < 2 || total.Cmp(ta.threshold) < 0 {
+		return ta.saveWindow(window)
+	}
+
+	finding := &agent.Finding{
+		AgentID:     ta.config.AgentID,
+		AlertType:   AlertTypeSplitTransfer,
+		Severity:    agent.SeverityInfo,
+		ChainID:     ta.config.ChainID,
+		BlockNumber: block.NumberU64(),
+		BlockHash:   block.Hash().Hex(),
+		TxHash:      txHash,
+		LogIndex:    transferLog.Index,
+		Metadata: map[string]string{
+			agent.MetadataAmount:    format.FormatUnits(total, ta.decimals),
+			agent.MetadataAmountRaw: total.String(),
+			agent.MetadataDecimals:  strconv.Itoa(ta.decimals),
+			agent.MetadataSymbol:    ta.symbol,
+			agent.MetadataToken:     ta.tokenAddress.Hex(),
+			MetadataTransfers:       strconv.Itoa(count),
+			MetadataWindowStart:     strconv.FormatUint(transfers[0].BlockNumber, 10),
+		},
+	}
+	if ta.config.GroupBy != GroupByReceiver {
+		finding.Metadata[agent.MetadataFrom] = event.From.Hex()
+	}
+	if ta.config.GroupBy != GroupBySender {
+		finding.Metadata[agent.MetadataTo] = event.To.Hex()
+	}
+	if err := ta.pipeline.notify(ctx, finding, tx); err != nil {
+		return err
+	}
+
+	// Reset the window only after notifying: a failed notify fails the tx, which the pool retries.
+	window.Transfers = nil
+	return ta.saveWindow(window)
+}
+
+func (ta *TransferAggregator) saveWindow(window *TransferWindow) error {
+	if err := ta.config.Windows.SaveTransferWindow(ta.config.AgentID, window, ta.windowTTL()); err != nil {
+		return fmt.Errorf("failed to save the transfer window: %v", err)
+	}
+	return nil
+}
+
+// mergeOldestTransfers merges the oldest transfers until the window fits the max size. The merged
+// transfer expires with the newer one, so the total stays exact and the old value can stay a bit longer.
+func mergeOldestTransfers(transfers []*WindowTransfer, max int) []*WindowTransfer {
+	for max > 1 && len(transfers) > max {
+		oldest, next := transfers[0], transfers[1]
+		value, _ := big.NewInt(0).SetString(oldest.Value, 10)
+		nextValue, _ := big.NewInt(0).SetString(next.Value, 10)
+		merged := *next
+		merged.Value = value.Add(value, nextValue).String()
+		merged.Count = oldest.count() + next.count()
+		transfers = append([]*WindowTransfer{&merged}, transfers[2:]...)
+	}
+	return transfers
+}
+
+// windowKey returns the key of the transfer window. The mints and the burns are not aggregated
+// under the zero address.
+func (ta *TransferAggregator) windowKey(event *contracts.IERC20Transfer) (string, bool) {
+	var zero common.Address
+	switch ta.config.GroupBy {
+	case GroupBySender:
+		return event.From.Hex(), event.From != zero
+	case GroupByReceiver:
+		return event.To.Hex(), event.To != zero
+	default:
+		return fmt.Sprintf("%s-%s", event.From.Hex(), event.To.Hex()), event.From != zero && event.To != zero
+	}
+}
+
+func (ta *TransferAggregator) expired(transfer *WindowTransfer, block *types.Block) bool {
+	if ta.config.WindowBlocks > 0 {
+		return transfer.BlockNumber+ta.config.WindowBlocks <= block.NumberU64()
+	}
+	return transfer.Timestamp+ta.config.WindowSeconds <= block.Time()
+}
+
+// windowTTL lets the inactive windows expire in the repository.
+func (ta *TransferAggregator) windowTTL() time.Duration {
+	if ta.config.WindowBlocks > 0 {
+		return clients.BlockTime * time.Duration(ta.config.WindowBlocks)
+	}
+	return time.Second * time.Duration(ta.config.WindowSeconds)
+}
@@ -0,0 +1,326 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/canercidam/large-tx-detector/clients"
+	"github.com/canercidam/large-tx-detector/contracts"
+	"github.com/canercidam/large-tx-detector/core/agent"
+	"github.com/canercidam/large-tx-detector/format"
+	"github.com/canercidam/large-tx-detector/labels"
+	"github.com/canercidam/large-tx-detector/rules"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Config vars
+var (
+	DefaultBackfillChunkBlocks uint64 = 2000
+)
+
+// Alert types
+const (
+	AlertTypeAnomalousTransfer = "anomalous-transfer"
+)
+
+// MetadataZScore is the number of standard deviations of the transfer from the mean.
+const MetadataZScore = "zScore"
+
+// TransferStats is the exponentially weighted moving mean and variance of the log10 transfer values.
+type TransferStats struct {
+	Count    uint64  `json:"count"`
+	Mean     float64 `json:"mean"`
+	Variance float64 `json:"variance"`
+	// BackfilledTo is the last block which was added by the backfill.
+	BackfilledTo uint64 `json:"backfilledTo"`
+	// LastBlock is the last block of which a transfer was added live.
+	LastBlock uint64 `json:"lastBlock"`
+}
+
+// add updates the stats and returns the z-score of the value before the update.
+func (stats *TransferStats) add(x, alpha float64) float64 {
+	var z float64
+	if stats.Variance > 0 {
+		z = (x - stats.Mean) / math.Sqrt(stats.Variance)
+	}
+	if stats.Count == 0 {
+		stats.Mean = x
+	} else {
+		delta := x - stats.Mean
+		stats.Mean += alpha * delta
+		stats.Variance = (1 - alpha) * (stats.Variance + alpha*delta*delta)
+	}
+	stats.Count++
+	return z
+}
+
+// StatsRepository persists the transfer stats.
+type StatsRepository interface {
+	SaveTransferStats(agentID string, stats *TransferStats) error
+	GetTransferStats(agentID string) (*TransferStats, error)
+}
+
+// AnomalyConfig contains the anomaly detector agent config parameters.
+type AnomalyConfig struct {
+	AgentID      string
+	ChainID      uint64
+	TokenAddress string
+	Symbol       string
+	Decimals     int
+	// Alpha is the weight of the new transfers in the moving stats.
+	Alpha float64
+	// ZScoreThreshold is the minimum z-score of the outliers.
+	ZScoreThreshold float64
+	// WarmupSamples is the number of transfers to see before alerting.
+	WarmupSamples uint64
+	// BackfillChunkBlocks is the block range of the log queries in the backfill. The range is
+	// halved when the provider rejects a query with too many results.
+	BackfillChunkBlocks uint64
+	Notifier            agent.Notifier
+	// Templates are used by the default notifier.
+	Templates *format.Templates
+	Client    *clients.RPC
+	Stats     StatsRepository
+	Tokens    TokenRepository
+	Labels    *labels.Registry
+	Ignore    *IgnoreList
+	Rules     *rules.Engine
+	Actions   agent.ActionRepository
+}
+
+// AnomalyDetector detects the transfers which are unusually large for the token. It keeps the
+// moving stats of the log10 transfer values and implements the agent.Agent interface.
+type AnomalyDetector struct {
+	config       *AnomalyConfig
+	tokenAddress common.Address
+	symbol       string
+	decimals     int
+	exp          *big.Int
+	stats        *TransferStats
+	pipeline     *findingPipeline
+	contract     *bind.BoundContract
+
+	currentBlock    uint64
+	currentReceipts []*types.Receipt
+	currentState    int
+}
+
+// NewAnomalyDetector creates a new anomaly detector.
+func NewAnomalyDetector(conf *AnomalyConfig) (*AnomalyDetector, error) {
+	if conf.Alpha <= 0 || conf.Alpha >= 1 {
+		return nil, fmt.Errorf("alpha must be between 0 and 1")
+	}
+	ad := &AnomalyDetector{config: conf}
+	ad.tokenAddress = common.HexToAddress(conf.TokenAddress)
+	ad.contract, _ = contracts.BindIERC20(ad.tokenAddress, nil, nil, nil)
+	ad.pipeline = newFindingPipeline(conf.AgentID, conf.Notifier, conf.Templates, conf.Labels, conf.Ignore, conf.Rules, conf.Actions)
+	return ad, nil
+}
+
+// Skip checks the logs bloom filter to see if we should skip this block entirely.
+func (ad *AnomalyDetector) Skip(block *types.Block, tx *types.Transaction) bool {
+	hasTokenAddress := block.Bloom().Test(ad.tokenAddress.Bytes())
+	hasTopic := block.Bloom().Test(transferTopicHash.Bytes())
+	return !(hasTokenAddress && hasTopic)
+}
+
+// ID returns the agent ID.
+func (ad *AnomalyDetector) ID() string {
+	return ad.config.AgentID
+}
+
+// Init inits the tx handling.
+func (ad *AnomalyDetector) Init(op *agent.Operation, tx *types.Transaction) {
+	ad.currentState = op.State
+}
+
+// Next tells if we have a next state to continue handling.
+func (ad *AnomalyDetector) Next() bool {
+	ad.currentState++
+	return ad.currentState < 2
+}
+
+// HandleTransaction scores the token transfers of the transaction and updates the stats.
+func (ad *AnomalyDetector) HandleTransaction(ctx context.Context, block *types.Block, tx *types.Transaction) error {
+	if err := ad.ensureState(ctx); err != nil {
+		return err
+	}
+	// The backfill has already counted the block.
+	if block.NumberU64() <= ad.stats.BackfilledTo {
+		return nil
+	}
+	if ad.currentBlock != block.NumberU64() {
+		receipts, err := getBlockReceipts(ctx, ad.config.Client, block)
+		if err != nil {
+			return err
+		}
+		ad.currentReceipts = receipts
+		ad.currentBlock = block.NumberU64()
+	}
+
+	// Update a copy of the stats and keep it only after notifying: a failed notify fails the tx,
+	// which the pool retries, and the transfers should not be counted twice.
+	stats := *ad.stats
+	var updated bool
+	for _, transferLog := range findLogs(ad.currentReceipts, tx, ad.tokenAddress, transferTopicHash) {
+		event, err := contracts.UnpackIERC20Transfer(ad.contract, transferLog)
+		if err != nil {
+			return fmt.Errorf("failed to unpack the event: %v", err)
+		}
+		x, ok := ad.logValue(event.Value)
+		if !ok {
+			continue
+		}
+		warm := stats.Count >= ad.config.WarmupSamples
+		z := stats.add(x, ad.config.Alpha)
+		stats.LastBlock = block.NumberU64()
+		updated = true
+		if !warm || z < ad.config.ZScoreThreshold {
+			continue
+		}
+		if err := ad.notify(ctx, block, tx, transferLog, event, z); err != nil {
+			return err
+		}
+	}
+	if !updated {
+		return nil
+	}
+	if err := ad.config.Stats.SaveTransferStats(ad.config.AgentID, &stats); err != nil {
+		return fmt.Errorf("failed to save the transfer stats: %v", err)
+	}
+	ad.stats = &stats
+	return nil
+}
+
+func (ad *AnomalyDetector) notify(ctx context.Context, block *types.Block, tx *types.Transaction, transferLog *types.Log, event *contracts.IERC20Transfer, z float64) error {
+	finding := &agent.Finding{
+		AgentID:     ad.config.AgentID,
+		AlertType:   AlertTypeAnomalousTransfer,
+		Severity:    agent.SeverityInfo,
+		ChainID:     ad.config.ChainID,
+		BlockNumber: block.NumberU64(),
+		BlockHash:   block.Hash().Hex(),
+		TxHash:      transferLog.TxHash.Hex(),
+		LogIndex:    transferLog.Index,
+		Metadata: map[string]string{
+			agent.MetadataFrom:      event.From.Hex(),
+			agent.MetadataTo:        event.To.Hex(),
+			agent.MetadataAmount:    format.FormatUnits(event.Value, ad.decimals),
+			agent.MetadataAmountRaw: event.Value.String(),
+			agent.MetadataDecimals:  strconv.Itoa(ad.decimals),
+			agent.MetadataSymbol:    ad.symbol,
+			agent.MetadataToken:     ad.tokenAddress.Hex(),
+			MetadataZScore:          strconv.FormatFloat(z, 'f', 2, 64),
+		},
+	}
+	return ad.pipeline.notify(ctx, finding, tx)
+}
+
+// Backfill warms up the stats with the transfers in the block range. It does not alert. The blocks
+// which were already added by a previous backfill or live are skipped.
+func (ad *AnomalyDetector) Backfill(ctx context.Context, fromBlock, toBlock uint64) error {
+	if err := ad.ensureState(ctx); err != nil {
+		return err
+	}
+	if fromBlock <= ad.stats.BackfilledTo {
+		fromBlock = ad.stats.BackfilledTo + 1
+	}
+	if fromBlock <= ad.stats.LastBlock {
+		fromBlock = ad.stats.LastBlock + 1
+	}
+	chunkBlocks := ad.config.BackfillChunkBlocks
+	if chunkBlocks == 0 {
+		chunkBlocks = DefaultBackfillChunkBlocks
+	}
+	for start := fromBlock; start <= toBlock; {
+		end := start + chunkBlocks - 1
+		if end > toBlock {
+			end = toBlock
+		}
+		logs, err := ad.config.Client.FilterLogs(ctx, ethereum.FilterQuery{
+			FromBlock: big.NewInt(0).SetUint64(start),
+			ToBlock:   big.NewInt(0).SetUint64(end),
+			Addresses: []common.Address{ad.tokenAddress},
+			Topics:    [][]common.Hash{{transferTopicHash}},
+		})
+		if err != nil && isTooManyResults(err) && chunkBlocks > 1 {
+			chunkBlocks /= 2
+			log.Printf("agent '%s' got too many logs for blocks %d-%d, retrying with %d blocks", ad.config.AgentID, start, end, chunkBlocks)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get the transfer logs of blocks %d-%d: %v", start, end, err)
+		}
+		for _, transferLog := range logs {
+			if transferLog.Removed {
+				continue
+			}
+			event, err := contracts.UnpackIERC20Transfer(ad.contract, &transferLog)
+			if err != nil {
+				return fmt.Errorf("failed to unpack the event: %v", err)
+			}
+			if x, ok := ad.logValue(event.Value); ok {
+				ad.stats.add(x, ad.config.Alpha)
+			}
+		}
+		ad.stats.BackfilledTo = end
+		if err := ad.config.Stats.SaveTransferStats(ad.config.AgentID, ad.stats); err != nil {
+			return fmt.Errorf("failed to save the transfer stats: %v", err)
+		}
+		start = end + 1
+	}
+	log.Printf("agent '%s' backfilled to block %d with %d transfers", ad.config.AgentID, ad.stats.BackfilledTo, ad.stats.Count)
+	return nil
+}
+
+// isTooManyResults tells if the provider rejected the log query because of the result size. The providers
+// do not have a common error code for it, so the messages of the common ones are matched.
+func isTooManyResults(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, s := range []string{"more than", "too many", "limit exceeded", "response size", "range is too large", "block range"} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// logValue returns the log10 of the value in token units. Zero values are skipped.
+func (ad *AnomalyDetector) logValue(value *big.Int) (float64, bool) {
+	if value.Sign() <= 0 {
+		return 0, false
+	}
+	f, _ := big.NewRat(0, 1).SetFrac(value, ad.exp).Float64()
+	return math.Log10(f), true
+}
+
+// ensureState ensures that we have the token metadata and the persisted stats.
+func (ad *AnomalyDetector) ensureState(ctx context.Context) error {
+	if ad.stats != nil {
+		return nil
+	}
+	token, err := resolveTokenMetadata(ctx, ad.config.Client, ad.config.Tokens, ad.tokenAddress, ad.config.Symbol, ad.config.Decimals)
+	if err != nil {
+		return err
+	}
+	stats, err := ad.config.Stats.GetTransferStats(ad.config.AgentID)
+	if err != nil {
+		return fmt.Errorf("failed to get the transfer stats: %v", err)
+	}
+	if stats == nil {
+		stats = &TransferStats{}
+	}
+	ad.symbol = token.Symbol
+	ad.decimals = token.Decimals
+	ad.exp = big.NewInt(0).Exp(big.NewInt(10), big.NewInt(int64(ad.decimals)), nil)
+	ad.stats = stats
+	return nil
+}
@@ -0,0 +1,92 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/canercidam/large-tx-detector/core/agent"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// memStats keeps the transfer stats in memory.
+type memStats struct {
+	saved *TransferStats
+}
+
+func (m *memStats) SaveTransferStats(agentID string, stats *TransferStats) error {
+	saved := *stats
+	m.saved = &saved
+	return nil
+}
+
+func (m *memStats) GetTransferStats(agentID string) (*TransferStats, error) {
+	return m.saved, nil
+}
+
+// failingNotifier fails the first notifications.
+type failingNotifier struct {
+	failures int
+	count    int
+}
+
+func (n *failingNotifier) Notify(ctx context.Context, finding *agent.Finding) error {
+	if n.failures > 0 {
+		n.failures--
+		return errors.New("failed")
+	}
+	n.count++
+	return nil
+}
+
+func TestAnomalySavesStatsAfterNotifying(t *testing.T) {
+	token := common.HexToAddress("0x1")
+	stats := &memStats{}
+	notifier := &failingNotifier{failures: 1}
+	ad, err := NewAnomalyDetector(&AnomalyConfig{
+		AgentID:         "anomaly-agent",
+		TokenAddress:    token.Hex(),
+		Alpha:           0.1,
+		ZScoreThreshold: 3,
+		Notifier:        notifier,
+		Stats:           stats,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ad.exp = big.NewInt(1)
+	ad.stats = &TransferStats{Count: 100, Mean: 2, Variance: 0.01}
+
+	tx := types.NewTransaction(0, token, big.NewInt(0), 0, big.NewInt(0), nil)
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(10)})
+	ad.currentBlock = block.NumberU64()
+	ad.currentReceipts = []*types.Receipt{{
+		TxHash: tx.Hash(),
+		Logs: []*types.Log{{
+			Address: token,
+			Topics:  []common.Hash{transferTopicHash, common.HexToHash("0xa"), common.HexToHash("0xb")},
+			Data:    common.LeftPadBytes(big.NewInt(1000000).Bytes(), 32),
+			TxHash:  tx.Hash(),
+		}},
+	}}
+
+	ctx := context.Background()
+	if err := ad.HandleTransaction(ctx, block, tx); err == nil {
+		t.Fatal("expected the failed notify to fail the tx")
+	}
+	if stats.saved != nil || ad.stats.Count != 100 {
+		t.Fatalf("expected the stats not to change, got count %d", ad.stats.Count)
+	}
+
+	if err := ad.HandleTransaction(ctx, block, tx); err != nil {
+		t.Fatal(err)
+	}
+	if notifier.count != 1 {
+		t.Fatalf("expected the retry to notify, got %d", notifier.count)
+	}
+	if stats.saved == nil || stats.saved.Count != 101 || ad.stats.Count != 101 {
+		t.Fatal("expected the transfer to be counted once")
+	}
+}
@@ -0,0 +1,274 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"strconv"
+
+	"github.com/canercidam/large-tx-detector/clients"
+	"github.com/canercidam/large-tx-detector/contracts"
+	"github.com/canercidam/large-tx-detector/core/agent"
+	"github.com/canercidam/large-tx-detector/format"
+	"github.com/canercidam/large-tx-detector/labels"
+	"github.com/canercidam/large-tx-detector/rules"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	approvalTopicHash = crypto.Keccak256Hash([]byte("Approval(address,address,uint256)"))
+)
+
+// Alert types
+const (
+	AlertTypeLargeApproval = "large-approval"
+)
+
+// Approval metadata keys
+const (
+	MetadataUnlimited  = "unlimited"
+	MetadataNewSpender = "newSpender"
+)
+
+// ApprovalConfig contains the approval monitor agent config parameters.
+type ApprovalConfig struct {
+	AgentID      string
+	ChainID      uint64
+	TokenAddress string
+	Symbol       string
+	Decimals     int
+	// Owners are the watched addresses. The addresses on the watchlist are also watched, if specified.
+	Owners    []string
+	Watchlist WatchlistRepository
+	// Threshold is the minimum allowance in token units. Only the unlimited allowances are alerted if it is zero.
+	Threshold uint64
+	// NewSpenderBlocks is how recent the spender contract must be deployed to be new. Zero disables the check.
+	NewSpenderBlocks uint64
+	Notifier         agent.Notifier
+	// Templates are used by the default notifier.
+	Templates *format.Templates
+	Client    *clients.RPC
+	Tokens    TokenRepository
+	Labels    *labels.Registry
+	Ignore    *IgnoreList
+	Rules     *rules.Engine
+	Actions   agent.ActionRepository
+}
+
+// ApprovalMonitor detects the large and the unlimited allowances which the watched addresses
+// grant, and implements the agent.Agent interface. The allowances to the unlabeled and the
+// newly deployed spenders have a higher severity.
+type ApprovalMonitor struct {
+	config       *ApprovalConfig
+	tokenAddress common.Address
+	owners       map[common.Address]bool
+	symbol       string
+	decimals     int
+	threshold    *big.Int
+	pipeline     *findingPipeline
+	contract     *bind.BoundContract
+
+	currentBlock    uint64
+	currentReceipts []*types.Receipt
+	currentState    int
+}
+
+// NewApprovalMonitor creates a new approval monitor.
+func NewApprovalMonitor(conf *ApprovalConfig) (*ApprovalMonitor, error) {
+	am := &ApprovalMonitor{config: conf, owners: make(map[common.Address]bool)}
+	for _, owner := range conf.Owners {
+		if !common.IsHexAddress(owner) {
+			return nil, fmt.Errorf("invalid owner address '%s'", owner)
+		}
+		am.owners[common.HexToAddress(owner)] = true
+	}
+	am.tokenAddress = common.HexToAddress(conf.TokenAddress)
+	am.contract, _ = contracts.BindIERC20(am.tokenAddress, nil, nil, nil)
+	am.pipeline = newFindingPipeline(conf.AgentID, conf.Notifier, conf.Templates, conf.Labels, conf.Ignore, conf.Rules, conf.Actions)
+	return am, nil
+}
+
+// Skip checks the logs bloom filter to see if we should skip this block entirely.
+func (am *ApprovalMonitor) Skip(block *types.Block, tx *types.Transaction) bool {
+	hasTokenAddress := block.Bloom().Test(am.tokenAddress.Bytes())
+	hasTopic := block.Bloom().Test(approvalTopicHash.Bytes())
+	return !(hasTokenAddress && hasTopic)
+}
+
+// ID returns the agent ID.
+func (am *ApprovalMonitor) ID() string {
+	return am.config.AgentID
+}
+
+// Init inits the tx handling.
+func (am *ApprovalMonitor) Init(op *agent.Operation, tx *types.Transaction) {
+	am.currentState = op.State
+}
+
+// Next tells if we have a next state to continue handling.
+func (am *ApprovalMonitor) Next() bool {
+	am.currentState++
+	return am.currentState < 2
+}
+
+// HandleTransaction checks the approvals of the transaction.
+func (am *ApprovalMonitor) HandleTransaction(ctx context.Context, block *types.Block, tx *types.Transaction) error {
+	if am.threshold == nil {
+		token, err := resolveTokenMetadata(ctx, am.config.Client, am.config.Tokens, am.tokenAddress, am.config.Symbol, am.config.Decimals)
+		if err != nil {
+			return err
+		}
+		am.symbol = token.Symbol
+		am.decimals = token.Decimals
+		exp := big.NewInt(0).Exp(big.NewInt(10), big.NewInt(int64(am.decimals)), nil)
+		am.threshold = big.NewInt(0).Mul(big.NewInt(0).SetUint64(am.config.Threshold), exp)
+	}
+	if am.currentBlock != block.NumberU64() {
+		receipts, err := getBlockReceipts(ctx, am.config.Client, block)
+		if err != nil {
+			return err
+		}
+		am.currentReceipts = receipts
+		am.currentBlock = block.NumberU64()
+	}
+
+	for _, approvalLog := range findLogs(am.currentReceipts, tx, am.tokenAddress, approvalTopicHash) {
+		event, err := contracts.UnpackIERC20Approval(am.contract, approvalLog)
+		if err != nil {
+			return fmt.Errorf("failed to unpack the event: %v", err)
+		}
+		if err := am.checkApproval(ctx, block, tx, approvalLog, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (am *ApprovalMonitor) checkApproval(ctx context.Context, block *types.Block, tx *types.Transaction, approvalLog *types.Log, event *contracts.IERC20Approval) error {
+	unlimited := event.Value.Cmp(math.MaxBig256) == 0
+	if !unlimited && (am.threshold.Sign() == 0 || event.Value.Cmp(am.threshold) < 0) {
+		return nil
+	}
+	watched, err := am.isWatched(event.Owner)
+	if err != nil {
+		return err
+	}
+	if !watched {
+		return nil
+	}
+	// The past state may not be available, so the alert is sent without the escalation.
+	newSpender, err := am.isNewContract(ctx, event.Spender, block)
+	if err != nil {
+		log.Printf("agent '%s' failed to check the spender %s: %v", am.config.AgentID, event.Spender.Hex(), err)
+	}
+
+	severity := agent.SeverityMedium
+	if unlimited {
+		severity = agent.SeverityHigh
+	}
+	finding := &agent.Finding{
+		AgentID:     am.config.AgentID,
+		AlertType:   AlertTypeLargeApproval,
+		ChainID:     am.config.ChainID,
+		BlockNumber: block.NumberU64(),
+		BlockHash:   block.Hash().Hex(),
+		TxHash:      approvalLog.TxHash.Hex(),
+		LogIndex:    approvalLog.Index,
+		Metadata: map[string]string{
+			agent.MetadataFrom:      event.Owner.Hex(),
+			agent.MetadataTo:        event.Spender.Hex(),
+			agent.MetadataAmount:    format.FormatUnits(event.Value, am.decimals),
+			agent.MetadataAmountRaw: event.Value.String(),
+			agent.MetadataDecimals:  strconv.Itoa(am.decimals),
+			agent.MetadataSymbol:    am.symbol,
+			agent.MetadataToken:     am.tokenAddress.Hex(),
+		},
+	}
+	if unlimited {
+		finding.Metadata[MetadataUnlimited] = "true"
+	}
+	if newSpender {
+		finding.Metadata[MetadataNewSpender] = "true"
+		severity = escalate(severity)
+	}
+	labeled, err := am.isLabeled(event.Spender)
+	if err != nil {
+		return err
+	}
+	if !labeled {
+		severity = escalate(severity)
+	}
+	finding.Severity = severity
+	return am.pipeline.notify(ctx, finding, tx)
+}
+
+// isLabeled tells if the address has a label.
+func (am *ApprovalMonitor) isLabeled(address common.Address) (bool, error) {
+	if am.config.Labels == nil {
+		return false, nil
+	}
+	label, err := am.config.Labels.Lookup(address.Hex())
+	if err != nil {
+		return false, fmt.Errorf("failed to get the spender label: %v", err)
+	}
+	return label != nil, nil
+}
+
+// isWatched tells if the owner is in the config or on the watchlist.
+func (am *ApprovalMonitor) isWatched(owner common.Address) (bool, error) {
+	if am.owners[owner] {
+		return true, nil
+	}
+	if am.config.Watchlist == nil {
+		return false, nil
+	}
+	entries, err := am.config.Watchlist.GetWatchlistEntries(owner.Hex())
+	if err != nil {
+		return false, fmt.Errorf("failed to get the watchlist entries: %v", err)
+	}
+	for _, entry := range entries {
+		if entry.Direction != DirectionIn && entry.matchesToken(am.tokenAddress.Hex(), am.symbol) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// isNewContract tells if the address has code now but did not have it NewSpenderBlocks ago.
+func (am *ApprovalMonitor) isNewContract(ctx context.Context, address common.Address, block *types.Block) (bool, error) {
+	if am.config.NewSpenderBlocks == 0 || block.NumberU64() <= am.config.NewSpenderBlocks {
+		return false, nil
+	}
+	code, err := am.config.Client.CodeAt(ctx, address, block.Number())
+	if err != nil {
+		return false, fmt.Errorf("failed to get the spender code: %v", err)
+	}
+	if len(code) == 0 {
+		return false, nil
+	}
+	pastBlock := big.NewInt(0).SetUint64(block.NumberU64() - am.config.NewSpenderBlocks)
+	pastCode, err := am.config.Client.CodeAt(ctx, address, pastBlock)
+	if err != nil {
+		return false, fmt.Errorf("failed to get the past spender code: %v", err)
+	}
+	return len(pastCode) == 0, nil
+}
+
+// escalate returns the next higher severity. Critical stays critical.
+func escalate(severity agent.Severity) agent.Severity {
+	switch severity {
+	case agent.SeverityInfo:
+		return agent.SeverityLow
+	case agent.SeverityLow:
+		return agent.SeverityMedium
+	case agent.SeverityMedium:
+		return agent.SeverityHigh
+	default:
+		return agent.SeverityCritical
+	}
+}
@@ -5,10 +5,14 @@ import (
 	"fmt"
 	"log"
 	"math/big"
+	"strconv"
+	"strings"
 
 	"github.com/canercidam/large-tx-detector/clients"
-	"github.com/canercidam/large-tx-detector/config"
 	"github.com/canercidam/large-tx-detector/contracts"
+	"github.com/canercidam/large-tx-detector/format"
+	"github.com/canercidam/large-tx-detector/labels"
+	"github.com/canercidam/large-tx-detector/rules"
 
 	"github.com/canercidam/large-tx-detector/core/agent"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
@@ -21,75 +25,124 @@ var (
 	transferTopicHash = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
 )
 
-// LargeTxNotification contains the notification data.
-type LargeTxNotification struct {
-	Hash   string
-	From   string
-	To     string
-	Value  float64
-	Symbol string
-}
+// defaultNotifierName is the template name of the default notifier.
+const defaultNotifierName = "log"
 
-// LargeTxNotifier sends notifications about large txs.
-type LargeTxNotifier interface {
-	Notify(context.Context, *LargeTxNotification) error
-}
+// Alert types
+const (
+	AlertTypeLargeTransfer     = "large-transfer"
+	AlertTypeWatchlistTransfer = "watchlist-transfer"
+	AlertTypeMint              = "mint"
+	AlertTypeBurn              = "burn"
+)
 
-type defaultLTNotifier struct{}
+// Transfer kinds
+const (
+	transferKindTransfer = "transfer"
+	transferKindMint     = "mint"
+	transferKindBurn     = "burn"
+)
+
+type defaultLTNotifier struct {
+	templates *format.Templates
+}
 
-func (dltn *defaultLTNotifier) Notify(ctx context.Context, notif *LargeTxNotification) error {
+func (dltn *defaultLTNotifier) Notify(ctx context.Context, finding *agent.Finding) error {
+	text, ok, err := dltn.templates.Render(defaultNotifierName, finding)
+	if err != nil {
+		return err
+	}
+	if ok {
+		log.Print(text)
+		return nil
+	}
 	log.Printf(
-		"notification: large tx %s detected from %s to %s of amount %.5f %s",
-		notif.Hash, notif.From, notif.To, notif.Value, notif.Symbol,
+		"notification: %s in %s from %s to %s of amount %s",
+		finding.AlertType, format.FindingRef(finding), finding.Metadata[agent.MetadataFrom], finding.Metadata[agent.MetadataTo], format.Amount(finding),
 	)
 	return nil
 }
 
-// LTDConfig contains the large tx detector agent config parameters.
+// TokenRepository caches the token metadata.
+type TokenRepository interface {
+	SaveTokenMetadata(*contracts.TokenMetadata) error
+	GetTokenMetadata(address string) (*contracts.TokenMetadata, error)
+}
+
+// LTDConfig contains the large tx detector agent config parameters. The token
+// symbol and decimals are read from the token contract unless they are specified.
 type LTDConfig struct {
 	AgentID      string
+	ChainID      uint64
 	TokenAddress string
 	Symbol       string
+	Decimals     int
 	Threshold    uint64
-	Notifier     LargeTxNotifier
-	Client       *clients.RPC
+	// USDThreshold is compared with the transfer value in USD if the price feed is specified.
+	// The token threshold is ignored if it is zero and the USD threshold is set.
+	USDThreshold uint64
+	// SeverityTiers set the finding severity by the transfer value. The transfers above
+	// any tier are large. The findings below all tiers are info.
+	SeverityTiers []*SeverityTier
+	// MintThreshold and BurnThreshold are the token thresholds of the transfers from and to the zero
+	// address, and the USDT Issue and Redeem events. The other thresholds are used if they are zero.
+	MintThreshold uint64
+	BurnThreshold uint64
+	PriceFeed     *PriceFeed
+	Notifier      agent.Notifier
+	Client        *clients.RPC
+	// Actions are checked before notifying, if specified.
+	Actions agent.ActionRepository
+	// Templates are used by the default notifier.
+	Templates *format.Templates
+	// Tokens cache the token metadata, if specified.
+	Tokens TokenRepository
+	// Labels are attached to the findings, if specified.
+	Labels *labels.Registry
+	// Watchlist alerts about the watched addresses with their own thresholds, if specified.
+	Watchlist WatchlistRepository
+	// Ignore suppresses the matching findings, if specified.
+	Ignore *IgnoreList
+	// Rules filter the findings with the expressions, if specified.
+	Rules *rules.Engine
 }
 
 // LargeTxDetector detects the large transactions and implements the agent.Agent interface.
 type LargeTxDetector struct {
-	config       *LTDConfig
-	tokenAddress common.Address
-	decimals     int
-	exp          *big.Int
-	threshold    *big.Int
-	notifier     LargeTxNotifier
-	client       *clients.RPC
-	contract     *bind.BoundContract
+	config        *LTDConfig
+	tokenAddress  common.Address
+	symbol        string
+	decimals      int
+	exp           *big.Int
+	threshold     *big.Int
+	mintThreshold *big.Int
+	burnThreshold *big.Int
+	usdThreshold  *big.Rat
+	tiers         severityTiers
+	pipeline      *findingPipeline
+	client        *clients.RPC
+	contract      *bind.BoundContract
+	supply        *bind.BoundContract
 
 	currentOp       *agent.Operation
 	currentTx       *types.Transaction
 	currentBlock    uint64
 	currentReceipts []*types.Receipt
 	currentState    int
+	currentPrice    *big.Rat
+	priceBlock      uint64
 }
 
 // NewLargeTxDetector creates a new large tx detector.
 func NewLargeTxDetector(conf *LTDConfig) *LargeTxDetector {
 	ltd := &LargeTxDetector{config: conf}
 	ltd.tokenAddress = common.HexToAddress(conf.TokenAddress)
-	ltd.decimals = config.Vars.WatchedTokenDecimals
-	ltd.notifier = conf.Notifier
 	ltd.client = conf.Client
 	ltd.contract, _ = contracts.BindIERC20(ltd.tokenAddress, nil, nil, nil)
+	ltd.supply, _ = contracts.BindTetherSupply(ltd.tokenAddress)
+	ltd.usdThreshold = big.NewRat(0, 1).SetInt(big.NewInt(0).SetUint64(conf.USDThreshold))
 
-	// Convert float amount to wei.
-	ltd.exp = big.NewInt(0).Exp(big.NewInt(10), big.NewInt(int64(ltd.decimals)), nil)
-	ltd.threshold = big.NewInt(0).Mul(big.NewInt(0).SetUint64(conf.Threshold), ltd.exp)
-
-	// Use default log notifier if a notifier was not specified.
-	if ltd.notifier == nil {
-		ltd.notifier = &defaultLTNotifier{}
-	}
+	ltd.pipeline = newFindingPipeline(conf.AgentID, conf.Notifier, conf.Templates, conf.Labels, conf.Ignore, conf.Rules, conf.Actions)
 
 	return ltd
 }
@@ -97,7 +150,8 @@ func NewLargeTxDetector(conf *LTDConfig) *LargeTxDetector {
 // Skip checks the logs bloom filter to see if we should skip this block entirely.
 func (ltd *LargeTxDetector) Skip(block *types.Block, tx *types.Transaction) bool {
 	hasTokenAddress := block.Bloom().Test(ltd.tokenAddress.Bytes())
-	hasTopic := block.Bloom().Test(transferTopicHash.Bytes())
+	hasTopic := block.Bloom().Test(transferTopicHash.Bytes()) ||
+		block.Bloom().Test(contracts.TetherIssueTopic.Bytes()) || block.Bloom().Test(contracts.TetherRedeemTopic.Bytes())
 	return !(hasTokenAddress && hasTopic)
 }
 
@@ -123,31 +177,220 @@ func (ltd *LargeTxDetector) Next() bool {
 
 // HandleTransaction handles a transaction using the block info.
 func (ltd *LargeTxDetector) HandleTransaction(ctx context.Context, block *types.Block, tx *types.Transaction) error {
+	if err := ltd.ensureTokenMetadata(ctx); err != nil {
+		return err
+	}
 	if err := ltd.ensureTxLogs(ctx, block); err != nil {
 		return err
 	}
 
-	transferLog, ok := ltd.findTransferLog(tx)
-	if !ok {
+	transferLog, event, kind, err := ltd.findTransfer(tx)
+	if err != nil {
+		return err
+	}
+	if transferLog == nil {
 		return nil
 	}
 
-	event, err := contracts.UnpackIERC20Transfer(ltd.contract, transferLog)
+	watchlists, err := ltd.matchWatchlists(event)
 	if err != nil {
-		return fmt.Errorf("failed to unpack the event: %v", err)
+		return err
 	}
+	usdValue, err := ltd.usdValue(ctx, block, event.Value)
+	if err != nil {
+		return err
+	}
+	large, severity := ltd.classify(kind, event.Value, usdValue)
+	if !large && len(watchlists) == 0 {
+		return nil
+	}
+
+	var alertType string
+	switch {
+	case !large:
+		alertType = AlertTypeWatchlistTransfer
+	case kind == transferKindMint:
+		alertType = AlertTypeMint
+	case kind == transferKindBurn:
+		alertType = AlertTypeBurn
+	default:
+		alertType = AlertTypeLargeTransfer
+	}
+	finding := &agent.Finding{
+		AgentID:     ltd.config.AgentID,
+		AlertType:   alertType,
+		Severity:    severity,
+		ChainID:     ltd.config.ChainID,
+		BlockNumber: block.NumberU64(),
+		BlockHash:   block.Hash().Hex(),
+		TxHash:      tx.Hash().Hex(),
+		LogIndex:    transferLog.Index,
+		Metadata: map[string]string{
+			agent.MetadataFrom:      event.From.Hex(),
+			agent.MetadataTo:        event.To.Hex(),
+			agent.MetadataAmount:    format.FormatUnits(event.Value, ltd.decimals),
+			agent.MetadataAmountRaw: event.Value.String(),
+			agent.MetadataDecimals:  strconv.Itoa(ltd.decimals),
+			agent.MetadataSymbol:    ltd.symbol,
+			agent.MetadataToken:     ltd.tokenAddress.Hex(),
+		},
+	}
+	if usdValue != nil {
+		finding.Metadata[agent.MetadataAmountUSD] = usdValue.FloatString(2)
+	}
+	if len(watchlists) > 0 {
+		finding.Metadata[agent.MetadataWatchlist] = strings.Join(watchlists, ",")
+	}
+	return ltd.pipeline.notify(ctx, finding, tx)
+}
+
+// classify tells if the transfer is large and its severity. A matching tier makes the transfer large
+// unless it is a mint or a burn below its own threshold. The transfers which are not large are info.
+func (ltd *LargeTxDetector) classify(kind string, value *big.Int, usdValue *big.Rat) (bool, agent.Severity) {
+	large := ltd.isLarge(kind, value, usdValue)
+	severity, tierMatched := ltd.tiers.match(value, usdValue)
+	if tierMatched && !ltd.hasKindThreshold(kind) {
+		large = true
+	}
+	if !large || !tierMatched {
+		return large, agent.SeverityInfo
+	}
+	return large, severity
+}
+
+// hasKindThreshold tells if the mints or the burns have their own threshold.
+func (ltd *LargeTxDetector) hasKindThreshold(kind string) bool {
+	return (kind == transferKindMint && ltd.mintThreshold.Sign() > 0) ||
+		(kind == transferKindBurn && ltd.burnThreshold.Sign() > 0)
+}
+
+// isLarge compares the transfer value with the thresholds. The mints and the burns have their own
+// thresholds, if specified. Only the severity tiers are used if they are specified without the thresholds.
+// The USD value is nil if it is not known.
+func (ltd *LargeTxDetector) isLarge(kind string, value *big.Int, usdValue *big.Rat) bool {
+	if kind == transferKindMint && ltd.mintThreshold.Sign() > 0 {
+		return value.Cmp(ltd.mintThreshold) >= 0
+	}
+	if kind == transferKindBurn && ltd.burnThreshold.Sign() > 0 {
+		return value.Cmp(ltd.burnThreshold) >= 0
+	}
+	if len(ltd.tiers) > 0 && ltd.config.Threshold == 0 && ltd.config.USDThreshold == 0 {
+		return false
+	}
+	if ltd.config.USDThreshold > 0 {
+		if usdValue != nil && usdValue.Cmp(ltd.usdThreshold) >= 0 {
+			return true
+		}
+		if ltd.config.Threshold == 0 {
+			return false
+		}
+	}
+	return value.Cmp(ltd.threshold) >= 0
+}
 
-	if event.Value.Cmp(ltd.threshold) < 0 {
+// matchWatchlists returns the names of the watchlists which the sender or the receiver
+// is on, with a matching direction and a threshold below the transfer value.
+func (ltd *LargeTxDetector) matchWatchlists(event *contracts.IERC20Transfer) ([]string, error) {
+	repo := ltd.config.Watchlist
+	if repo == nil {
+		return nil, nil
+	}
+
+	value := big.NewRat(0, 1).SetFrac(event.Value, ltd.exp)
+	watched := []struct {
+		address   common.Address
+		direction string
+	}{
+		{address: event.From, direction: DirectionOut},
+		{address: event.To, direction: DirectionIn},
+	}
+	var names []string
+	for _, w := range watched {
+		entries, err := repo.GetWatchlistEntries(w.address.Hex())
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the watchlist entries: %v", err)
+		}
+		for _, entry := range entries {
+			if entry.Direction != DirectionBoth && entry.Direction != w.direction {
+				continue
+			}
+			if !entry.matchesToken(ltd.tokenAddress.Hex(), ltd.symbol) {
+				continue
+			}
+			threshold, ok := big.NewRat(0, 1).SetString(entry.Threshold)
+			if !ok || value.Cmp(threshold) < 0 {
+				continue
+			}
+			if !containsString(names, entry.Watchlist) {
+				names = append(names, entry.Watchlist)
+			}
+		}
+	}
+	return names, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// usdValue values the transfer with the latest token price, which is read once per block, so the
+// replayed old blocks are valued at the current price. It returns nil if the price is not available
+// and the token threshold can be used instead, or an error to retry the tx if only the USD value can tell.
+func (ltd *LargeTxDetector) usdValue(ctx context.Context, block *types.Block, value *big.Int) (*big.Rat, error) {
+	if ltd.config.PriceFeed == nil {
+		return nil, nil
+	}
+	if ltd.priceBlock != block.NumberU64() {
+		price, err := ltd.config.PriceFeed.LatestPrice(ctx)
+		if err != nil && ltd.requiresPrice() {
+			return nil, fmt.Errorf("failed to get the %s price: %v", ltd.symbol, err)
+		}
+		if err != nil {
+			log.Printf("failed to get the %s price at block %d, using the token threshold: %v", ltd.symbol, block.NumberU64(), err)
+		}
+		ltd.currentPrice = price
+		ltd.priceBlock = block.NumberU64()
+	}
+	if ltd.currentPrice == nil {
+		return nil, nil
+	}
+	amount := big.NewRat(0, 1).SetFrac(value, ltd.exp)
+	return amount.Mul(amount, ltd.currentPrice), nil
+}
+
+// requiresPrice tells if the large transfers can only be told by the USD value, without a token threshold.
+func (ltd *LargeTxDetector) requiresPrice() bool {
+	if ltd.config.Threshold > 0 {
+		return false
+	}
+	return ltd.config.USDThreshold > 0 || HasUSDTiers(ltd.config.SeverityTiers)
+}
+
+// ensureTokenMetadata ensures that we know the token symbol and decimals, and
+// the threshold in the base units. The metadata is read from the contract once and then cached.
+func (ltd *LargeTxDetector) ensureTokenMetadata(ctx context.Context) error {
+	if ltd.threshold != nil {
 		return nil
 	}
 
-	return ltd.notifier.Notify(ctx, &LargeTxNotification{
-		Hash:   tx.Hash().Hex(),
-		From:   event.From.Hex(),
-		To:     event.To.Hex(),
-		Value:  ltd.readableAmount(event.Value),
-		Symbol: ltd.config.Symbol,
-	})
+	token, err := resolveTokenMetadata(ctx, ltd.client, ltd.config.Tokens, ltd.tokenAddress, ltd.config.Symbol, ltd.config.Decimals)
+	if err != nil {
+		return err
+	}
+
+	ltd.symbol = token.Symbol
+	ltd.decimals = token.Decimals
+	ltd.exp = big.NewInt(0).Exp(big.NewInt(10), big.NewInt(int64(ltd.decimals)), nil)
+	ltd.threshold = big.NewInt(0).Mul(big.NewInt(0).SetUint64(ltd.config.Threshold), ltd.exp)
+	ltd.mintThreshold = big.NewInt(0).Mul(big.NewInt(0).SetUint64(ltd.config.MintThreshold), ltd.exp)
+	ltd.burnThreshold = big.NewInt(0).Mul(big.NewInt(0).SetUint64(ltd.config.BurnThreshold), ltd.exp)
+	ltd.tiers = newSeverityTiers(ltd.config.SeverityTiers, ltd.exp)
+	return nil
 }
 
 // ensureTxLogs ensures that we have the tx logs for the newest block.
@@ -156,19 +399,49 @@ func (ltd *LargeTxDetector) ensureTxLogs(ctx context.Context, block *types.Block
 	if currentBlock == ltd.currentBlock {
 		return nil
 	}
-	var txHashes []common.Hash
-	for _, tx := range block.Transactions() {
-		txHashes = append(txHashes, tx.Hash())
-	}
-	receipts, err := ltd.client.BatchGetTransactionReceipt(ctx, txHashes)
+	receipts, err := getBlockReceipts(ctx, ltd.client, block)
 	if err != nil {
-		return fmt.Errorf("failed to get the transaction logs for block %d: %v", currentBlock, err)
+		return err
 	}
 	ltd.currentReceipts = receipts
 	ltd.currentBlock = currentBlock
 	return nil
 }
 
+// findTransfer finds the token transfer of the tx and classifies it. The USDT Issue and Redeem
+// events are returned as the transfers from and to the zero address since the owner is not in the events.
+func (ltd *LargeTxDetector) findTransfer(tx *types.Transaction) (*types.Log, *contracts.IERC20Transfer, string, error) {
+	if transferLog, ok := ltd.findTransferLog(tx); ok {
+		event, err := contracts.UnpackIERC20Transfer(ltd.contract, transferLog)
+		if err != nil {
+			return nil, nil, "", fmt.Errorf("failed to unpack the event: %v", err)
+		}
+		switch {
+		case event.From == (common.Address{}):
+			return transferLog, event, transferKindMint, nil
+		case event.To == (common.Address{}):
+			return transferLog, event, transferKindBurn, nil
+		default:
+			return transferLog, event, transferKindTransfer, nil
+		}
+	}
+
+	for _, topic := range []common.Hash{contracts.TetherIssueTopic, contracts.TetherRedeemTopic} {
+		for _, supplyLog := range findLogs(ltd.currentReceipts, tx, ltd.tokenAddress, topic) {
+			supplyEvent, err := contracts.UnpackTetherSupplyEvent(ltd.supply, supplyLog)
+			if err != nil {
+				return nil, nil, "", fmt.Errorf("failed to unpack the supply event: %v", err)
+			}
+			event := &contracts.IERC20Transfer{Value: supplyEvent.Amount, Raw: *supplyLog}
+			if topic == contracts.TetherIssueTopic {
+				return supplyLog, event, transferKindMint, nil
+			}
+			return supplyLog, event, transferKindBurn, nil
+		}
+	}
+	return nil, nil, "", nil
+}
+
 func (ltd *LargeTxDetector) findTransferLog(tx *types.Transaction) (*types.Log, bool) {
 	for _, receipt := range ltd.currentReceipts {
 		if receipt.TxHash.Hex() != tx.Hash().Hex() {
@@ -187,7 +460,3 @@ func (ltd *LargeTxDetector) findTransferLog(tx *types.Transaction) (*types.Log,
 	}
 	return nil, false
 }
-
-func (ltd *LargeTxDetector) readableAmount(realAmount *big.Int) float64 {
-	return float64(big.NewInt(0).Div(realAmount, ltd.exp).Uint64())
-}
@@ -0,0 +1,103 @@
+package agents
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/canercidam/large-tx-detector/core/agent"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestLargeTxUSDValueWithStalePrice(t *testing.T) {
+	tests := []struct {
+		name      string
+		conf      *LTDConfig
+		expectErr bool
+	}{
+		{name: "usd threshold only", conf: &LTDConfig{USDThreshold: 1000000}, expectErr: true},
+		{name: "usd tiers only", conf: &LTDConfig{SeverityTiers: []*SeverityTier{{Severity: "high", Threshold: 1000000, USD: true}}}, expectErr: true},
+		{name: "token threshold", conf: &LTDConfig{Threshold: 1000000, USDThreshold: 1000000}, expectErr: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.conf.PriceFeed = newTestPriceFeed(t, &fakePriceFeed{
+				decimals:        8,
+				roundID:         10,
+				answer:          100000000,
+				updatedAt:       time.Now().Add(-time.Hour * 2),
+				answeredInRound: 10,
+			})
+			ltd := NewLargeTxDetector(test.conf)
+			ltd.exp = big.NewInt(1000000)
+			block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(1)})
+
+			usdValue, err := ltd.usdValue(context.Background(), block, big.NewInt(5000000))
+			if test.expectErr && err == nil {
+				t.Fatal("expected an error to retry the tx")
+			}
+			if !test.expectErr && (err != nil || usdValue != nil) {
+				t.Fatalf("expected to fall back to the token threshold, got %v and %v", usdValue, err)
+			}
+		})
+	}
+}
+
+func TestLargeTxUSDValue(t *testing.T) {
+	ltd := NewLargeTxDetector(&LTDConfig{USDThreshold: 1000000})
+	ltd.config.PriceFeed = newTestPriceFeed(t, &fakePriceFeed{
+		decimals:        8,
+		roundID:         10,
+		answer:          200000000,
+		updatedAt:       time.Now(),
+		answeredInRound: 10,
+	})
+	ltd.exp = big.NewInt(1000000)
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(1)})
+
+	usdValue, err := ltd.usdValue(context.Background(), block, big.NewInt(5000000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usdValue.FloatString(2) != "10.00" {
+		t.Fatalf("unexpected usd value %s", usdValue.FloatString(2))
+	}
+}
+
+func TestLargeTxClassify(t *testing.T) {
+	ltd := NewLargeTxDetector(&LTDConfig{
+		Symbol:        "USDT",
+		Decimals:      6,
+		Threshold:     1000,
+		MintThreshold: 5000,
+		SeverityTiers: []*SeverityTier{{Severity: agent.SeverityLow, Threshold: 100}, {Severity: agent.SeverityHigh, Threshold: 10000}},
+	})
+	if err := ltd.ensureTokenMetadata(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	tokens := func(n int64) *big.Int {
+		return big.NewInt(0).Mul(big.NewInt(n), ltd.exp)
+	}
+
+	tests := []struct {
+		name     string
+		kind     string
+		value    *big.Int
+		large    bool
+		severity agent.Severity
+	}{
+		{name: "transfer below tiers", kind: transferKindTransfer, value: tokens(50), large: false, severity: agent.SeverityInfo},
+		{name: "transfer in low tier", kind: transferKindTransfer, value: tokens(200), large: true, severity: agent.SeverityLow},
+		{name: "mint in low tier below its threshold", kind: transferKindMint, value: tokens(2000), large: false, severity: agent.SeverityInfo},
+		{name: "mint above its threshold", kind: transferKindMint, value: tokens(6000), large: true, severity: agent.SeverityLow},
+		{name: "mint in high tier", kind: transferKindMint, value: tokens(20000), large: true, severity: agent.SeverityHigh},
+		{name: "burn in low tier", kind: transferKindBurn, value: tokens(200), large: true, severity: agent.SeverityLow},
+	}
+	for _, test := range tests {
+		large, severity := ltd.classify(test.kind, test.value, nil)
+		if large != test.large || severity != test.severity {
+			t.Errorf("%s: expected %v and %s, got %v and %s", test.name, test.large, test.severity, large, severity)
+		}
+	}
+}
@@ -0,0 +1,344 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/canercidam/large-tx-detector/clients"
+	"github.com/canercidam/large-tx-detector/contracts"
+	"github.com/canercidam/large-tx-detector/core/agent"
+	"github.com/canercidam/large-tx-detector/format"
+	"github.com/canercidam/large-tx-detector/rules"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Alert types
+const (
+	AlertTypeSupplyChange = "supply-change"
+)
+
+// Supply metadata keys
+const (
+	MetadataPreviousSupply    = "previousSupply"
+	MetadataTotalSupply       = "totalSupply"
+	MetadataSupplyChange      = "supplyChange"
+	MetadataChangePercent     = "changePercent"
+	MetadataEventChange       = "eventChange"
+	MetadataUnexplainedChange = "unexplainedChange"
+	MetadataPreviousBlock     = "previousBlock"
+)
+
+// SupplySnapshot is the last totalSupply read and the net amount which the mint and burn
+// events have changed the supply since then.
+type SupplySnapshot struct {
+	BlockNumber uint64 `json:"blockNumber"`
+	TotalSupply string `json:"totalSupply"`
+	EventChange string `json:"eventChange"`
+	// EventBlock is the last block of which the events were counted.
+	EventBlock uint64 `json:"eventBlock"`
+}
+
+// SupplyRepository persists the supply snapshots.
+type SupplyRepository interface {
+	SaveSupplySnapshot(agentID string, snapshot *SupplySnapshot) error
+	GetSupplySnapshot(agentID string) (*SupplySnapshot, error)
+}
+
+// SupplyConfig contains the supply monitor agent config parameters. The supply change is
+// alerted if it reaches any of the thresholds.
+type SupplyConfig struct {
+	AgentID      string
+	ChainID      uint64
+	TokenAddress string
+	Symbol       string
+	Decimals     int
+	// IntervalBlocks is the number of blocks between the totalSupply reads.
+	IntervalBlocks uint64
+	// Threshold is the absolute change in token units. Zero disables it.
+	Threshold uint64
+	// PercentThreshold is the change in percent of the previous supply. Zero disables it.
+	PercentThreshold float64
+	Notifier         agent.Notifier
+	// Templates are used by the default notifier.
+	Templates *format.Templates
+	Client    *clients.RPC
+	Snapshots SupplyRepository
+	Tokens    TokenRepository
+	Ignore    *IgnoreList
+	Rules     *rules.Engine
+	Actions   agent.ActionRepository
+}
+
+// SupplyMonitor reads the token totalSupply at an interval and alerts about the large changes.
+// The change is cross-checked with the mint and the burn events in the blocks between the reads.
+// It implements the agent.BlockAgent interface.
+type SupplyMonitor struct {
+	config       *SupplyConfig
+	tokenAddress common.Address
+	symbol       string
+	decimals     int
+	threshold    *big.Int
+	pipeline     *findingPipeline
+	caller       *contracts.IERC20Caller
+	contract     *bind.BoundContract
+	supply       *bind.BoundContract
+}
+
+// NewSupplyMonitor creates a new supply monitor.
+func NewSupplyMonitor(conf *SupplyConfig) (*SupplyMonitor, error) {
+	if conf.IntervalBlocks == 0 {
+		return nil, fmt.Errorf("the interval must be at least one block")
+	}
+	sm := &SupplyMonitor{config: conf}
+	sm.tokenAddress = common.HexToAddress(conf.TokenAddress)
+	var err error
+	sm.caller, err = contracts.NewIERC20Caller(sm.tokenAddress, conf.Client)
+	if err != nil {
+		return nil, err
+	}
+	sm.contract, _ = contracts.BindIERC20(sm.tokenAddress, nil, nil, nil)
+	sm.supply, _ = contracts.BindTetherSupply(sm.tokenAddress)
+	sm.pipeline = newFindingPipeline(conf.AgentID, conf.Notifier, conf.Templates, nil, conf.Ignore, conf.Rules, conf.Actions)
+	return sm, nil
+}
+
+// ID returns the agent ID.
+func (sm *SupplyMonitor) ID() string {
+	return sm.config.AgentID
+}
+
+// HandleBlock counts the mint and burn events of the block, and compares the totalSupply with
+// the last read at the interval. The snapshot is saved after each block so that the repeated
+// blocks are not counted twice.
+func (sm *SupplyMonitor) HandleBlock(ctx context.Context, block *types.Block) error {
+	if sm.threshold == nil {
+		token, err := resolveTokenMetadata(ctx, sm.config.Client, sm.config.Tokens, sm.tokenAddress, sm.config.Symbol, sm.config.Decimals)
+		if err != nil {
+			return err
+		}
+		sm.symbol = token.Symbol
+		sm.decimals = token.Decimals
+		exp := big.NewInt(0).Exp(big.NewInt(10), big.NewInt(int64(sm.decimals)), nil)
+		sm.threshold = big.NewInt(0).Mul(big.NewInt(0).SetUint64(sm.config.Threshold), exp)
+	}
+
+	snapshot, err := sm.config.Snapshots.GetSupplySnapshot(sm.config.AgentID)
+	if err != nil {
+		return fmt.Errorf("failed to get the supply snapshot: %v", err)
+	}
+	if snapshot == nil {
+		return sm.saveSnapshot(ctx, block)
+	}
+	if block.NumberU64() <= snapshot.EventBlock {
+		return nil
+	}
+	// The events of a block which was skipped after failing were not counted and would show up
+	// as an unexplained change, so start over at this block like for a pruned state.
+	if block.NumberU64() > snapshot.EventBlock+1 {
+		log.Printf("agent '%s' missed the events after block %d, restarting at block %d", sm.config.AgentID, snapshot.EventBlock, block.NumberU64())
+		return sm.saveSnapshot(ctx, block)
+	}
+
+	eventChange, ok := big.NewInt(0).SetString(snapshot.EventChange, 10)
+	if !ok {
+		eventChange = big.NewInt(0)
+	}
+	blockChange, err := sm.eventChange(ctx, block)
+	if err != nil {
+		return err
+	}
+	eventChange.Add(eventChange, blockChange)
+
+	if block.NumberU64() < snapshot.BlockNumber+sm.config.IntervalBlocks {
+		snapshot.EventChange = eventChange.String()
+		snapshot.EventBlock = block.NumberU64()
+		if err := sm.config.Snapshots.SaveSupplySnapshot(sm.config.AgentID, snapshot); err != nil {
+			return fmt.Errorf("failed to save the supply snapshot: %v", err)
+		}
+		return nil
+	}
+
+	previousSupply, ok := big.NewInt(0).SetString(snapshot.TotalSupply, 10)
+	if !ok {
+		return sm.saveSnapshot(ctx, block)
+	}
+	totalSupply, err := sm.totalSupply(ctx, block.Number())
+	if isMissingState(err) {
+		log.Printf("agent '%s' cannot read the total supply at block %d, restarting at the head: %v", sm.config.AgentID, block.NumberU64(), err)
+		return sm.rebase(ctx)
+	}
+	if err != nil {
+		return err
+	}
+	change := big.NewInt(0).Sub(totalSupply, previousSupply)
+	if exceeds, percent := sm.exceeds(change, previousSupply); exceeds {
+		finding := sm.makeFinding(block, snapshot.BlockNumber, previousSupply, totalSupply, change, eventChange, percent)
+		if err := sm.pipeline.notify(ctx, finding, nil); err != nil {
+			return err
+		}
+	}
+	return sm.save(block.NumberU64(), totalSupply)
+}
+
+// exceeds compares the change with the thresholds and returns the change in percent.
+func (sm *SupplyMonitor) exceeds(change, previousSupply *big.Int) (bool, float64) {
+	abs := big.NewInt(0).Abs(change)
+	var percent float64
+	if previousSupply.Sign() > 0 {
+		percent, _ = big.NewRat(0, 1).SetFrac(big.NewInt(0).Mul(change, big.NewInt(100)), previousSupply).Float64()
+	}
+	if abs.Sign() == 0 {
+		return false, percent
+	}
+	if sm.threshold.Sign() > 0 && abs.Cmp(sm.threshold) >= 0 {
+		return true, percent
+	}
+	if sm.config.PercentThreshold > 0 && previousSupply.Sign() > 0 {
+		absPercent := percent
+		if absPercent < 0 {
+			absPercent = -absPercent
+		}
+		return absPercent >= sm.config.PercentThreshold, percent
+	}
+	return false, percent
+}
+
+// makeFinding makes a high severity finding, which is critical if the change does not match the events.
+func (sm *SupplyMonitor) makeFinding(
+	block *types.Block, previousBlock uint64, previousSupply, totalSupply, change, eventChange *big.Int, percent float64,
+) *agent.Finding {
+	finding := &agent.Finding{
+		AgentID:     sm.config.AgentID,
+		AlertType:   AlertTypeSupplyChange,
+		Severity:    agent.SeverityHigh,
+		ChainID:     sm.config.ChainID,
+		BlockNumber: block.NumberU64(),
+		BlockHash:   block.Hash().Hex(),
+		Metadata: map[string]string{
+			agent.MetadataAmount:    format.FormatUnits(big.NewInt(0).Abs(change), sm.decimals),
+			agent.MetadataAmountRaw: big.NewInt(0).Abs(change).String(),
+			agent.MetadataDecimals:  strconv.Itoa(sm.decimals),
+			agent.MetadataSymbol:    sm.symbol,
+			agent.MetadataToken:     sm.tokenAddress.Hex(),
+			MetadataPreviousSupply:  format.FormatUnits(previousSupply, sm.decimals),
+			MetadataTotalSupply:     format.FormatUnits(totalSupply, sm.decimals),
+			MetadataSupplyChange:    format.FormatUnits(change, sm.decimals),
+			MetadataChangePercent:   strconv.FormatFloat(percent, 'f', 4, 64),
+			MetadataEventChange:     format.FormatUnits(eventChange, sm.decimals),
+			MetadataPreviousBlock:   strconv.FormatUint(previousBlock, 10),
+		},
+	}
+	if unexplained := big.NewInt(0).Sub(change, eventChange); unexplained.Sign() != 0 {
+		finding.Severity = agent.SeverityCritical
+		finding.Metadata[MetadataUnexplainedChange] = format.FormatUnits(unexplained, sm.decimals)
+	}
+	return finding
+}
+
+// saveSnapshot reads the totalSupply and starts a new snapshot.
+func (sm *SupplyMonitor) saveSnapshot(ctx context.Context, block *types.Block) error {
+	totalSupply, err := sm.totalSupply(ctx, block.Number())
+	if isMissingState(err) {
+		return sm.rebase(ctx)
+	}
+	if err != nil {
+		return err
+	}
+	return sm.save(block.NumberU64(), totalSupply)
+}
+
+// rebase starts a new snapshot at the head when the state of the block is no longer available,
+// e.g. after catching up on a full node. The blocks until the head are then skipped.
+func (sm *SupplyMonitor) rebase(ctx context.Context) error {
+	head, err := sm.config.Client.BlockNumber(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get the latest block: %v", err)
+	}
+	headNumber := big.NewInt(0).SetUint64(head)
+	totalSupply, err := sm.totalSupply(ctx, headNumber)
+	if err != nil {
+		return err
+	}
+	return sm.save(head, totalSupply)
+}
+
+func (sm *SupplyMonitor) save(blockNumber uint64, totalSupply *big.Int) error {
+	return sm.config.Snapshots.SaveSupplySnapshot(sm.config.AgentID, &SupplySnapshot{
+		BlockNumber: blockNumber,
+		TotalSupply: totalSupply.String(),
+		EventChange: "0",
+		EventBlock:  blockNumber,
+	})
+}
+
+func (sm *SupplyMonitor) totalSupply(ctx context.Context, blockNumber *big.Int) (*big.Int, error) {
+	totalSupply, err := sm.caller.TotalSupply(&bind.CallOpts{Context: ctx, BlockNumber: blockNumber})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the total supply at block %s: %v", blockNumber, err)
+	}
+	return totalSupply, nil
+}
+
+// isMissingState tells if the node has pruned the state of the block.
+func isMissingState(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "missing trie node") || strings.Contains(msg, "state is not available") ||
+		strings.Contains(msg, "pruned")
+}
+
+// eventChange sums the mints and the burns in the block: the transfers from and to the zero
+// address, and the USDT Issue and Redeem events.
+func (sm *SupplyMonitor) eventChange(ctx context.Context, block *types.Block) (*big.Int, error) {
+	change := big.NewInt(0)
+	if !block.Bloom().Test(sm.tokenAddress.Bytes()) {
+		return change, nil
+	}
+	blockHash := block.Hash()
+	logs, err := sm.config.Client.FilterLogs(ctx, ethereum.FilterQuery{
+		BlockHash: &blockHash,
+		Addresses: []common.Address{sm.tokenAddress},
+		Topics:    [][]common.Hash{{transferTopicHash, contracts.TetherIssueTopic, contracts.TetherRedeemTopic}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the supply events of block %d: %v", block.NumberU64(), err)
+	}
+	for i := range logs {
+		eventLog := &logs[i]
+		if eventLog.Removed {
+			continue
+		}
+		if eventLog.Topics[0] == transferTopicHash {
+			event, err := contracts.UnpackIERC20Transfer(sm.contract, eventLog)
+			if err != nil {
+				return nil, fmt.Errorf("failed to unpack the event: %v", err)
+			}
+			if event.From == (common.Address{}) {
+				change.Add(change, event.Value)
+			}
+			if event.To == (common.Address{}) {
+				change.Sub(change, event.Value)
+			}
+			continue
+		}
+		event, err := contracts.UnpackTetherSupplyEvent(sm.supply, eventLog)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unpack the supply event: %v", err)
+		}
+		if eventLog.Topics[0] == contracts.TetherIssueTopic {
+			change.Add(change, event.Amount)
+		} else {
+			change.Sub(change, event.Amount)
+		}
+	}
+	return change, nil
+}
@@ -0,0 +1,87 @@
+package agents
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/canercidam/large-tx-detector/clients"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// memSnapshots keeps the supply snapshots in memory.
+type memSnapshots struct {
+	snapshot *SupplySnapshot
+}
+
+func (m *memSnapshots) SaveSupplySnapshot(agentID string, snapshot *SupplySnapshot) error {
+	m.snapshot = snapshot
+	return nil
+}
+
+func (m *memSnapshots) GetSupplySnapshot(agentID string) (*SupplySnapshot, error) {
+	return m.snapshot, nil
+}
+
+// totalSupplyServer replies to every eth_call with the total supply.
+func totalSupplyServer(t *testing.T, totalSupply int64) *clients.RPC {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Method != "eth_call" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      req.ID,
+			"result":  "0x" + hex.EncodeToString(common.LeftPadBytes(big.NewInt(totalSupply).Bytes(), 32)),
+		})
+	}))
+	t.Cleanup(srv.Close)
+
+	client, err := clients.NewRPC(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestSupplyRestartsAfterSkippedBlock(t *testing.T) {
+	snapshots := &memSnapshots{snapshot: &SupplySnapshot{BlockNumber: 100, TotalSupply: "1000", EventChange: "0", EventBlock: 101}}
+	notifier := &countingNotifier{}
+	sm, err := NewSupplyMonitor(&SupplyConfig{
+		AgentID:        "supply-agent",
+		TokenAddress:   "0x1",
+		IntervalBlocks: 5,
+		Threshold:      1,
+		Notifier:       notifier,
+		Client:         totalSupplyServer(t, 2000),
+		Snapshots:      snapshots,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sm.threshold = big.NewInt(1)
+
+	// The block 102, which minted 1000, was skipped.
+	for _, number := range []int64{103, 104, 105} {
+		block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(number)})
+		if err := sm.HandleBlock(context.Background(), block); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if notifier.count != 0 {
+		t.Fatal("expected no alert about the events of the skipped block")
+	}
+	if snapshots.snapshot.BlockNumber != 103 || snapshots.snapshot.TotalSupply != "2000" || snapshots.snapshot.EventBlock != 105 {
+		t.Fatalf("expected the snapshot to restart at the next block, got %+v", snapshots.snapshot)
+	}
+}
@@ -0,0 +1,104 @@
+package agents
+
+import (
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/canercidam/large-tx-detector/core/agent"
+)
+
+// suppressedFindings counts the findings suppressed by the ignore rules, by agent ID and rule name.
+var suppressedFindings = expvar.NewMap("suppressedFindings")
+
+// IgnoreRule matches the findings which should not be notified about. All specified
+// conditions must match.
+type IgnoreRule struct {
+	Name string `json:"name"`
+	// Address matches either the sender or the receiver.
+	Address      string `json:"address,omitempty"`
+	From         string `json:"from,omitempty"`
+	To           string `json:"to,omitempty"`
+	FromCategory string `json:"fromCategory,omitempty"`
+	ToCategory   string `json:"toCategory,omitempty"`
+	// SameLabel matches the transfers between the addresses with the same label, e.g. the wallets of an exchange.
+	SameLabel bool `json:"sameLabel,omitempty"`
+}
+
+func (rule *IgnoreRule) validate() error {
+	if len(rule.Name) == 0 {
+		return errors.New("rule name is required")
+	}
+	if len(rule.Address) == 0 && len(rule.From) == 0 && len(rule.To) == 0 &&
+		len(rule.FromCategory) == 0 && len(rule.ToCategory) == 0 && !rule.SameLabel {
+		return fmt.Errorf("rule '%s' has no conditions", rule.Name)
+	}
+	return nil
+}
+
+// matches tells if the rule matches the annotated finding.
+func (rule *IgnoreRule) matches(finding *agent.Finding) bool {
+	from, to := finding.Metadata[agent.MetadataFrom], finding.Metadata[agent.MetadataTo]
+	if len(rule.Address) > 0 && !strings.EqualFold(rule.Address, from) && !strings.EqualFold(rule.Address, to) {
+		return false
+	}
+	if len(rule.From) > 0 && !strings.EqualFold(rule.From, from) {
+		return false
+	}
+	if len(rule.To) > 0 && !strings.EqualFold(rule.To, to) {
+		return false
+	}
+	if len(rule.FromCategory) > 0 && !strings.EqualFold(rule.FromCategory, finding.Metadata[agent.MetadataFromCategory]) {
+		return false
+	}
+	if len(rule.ToCategory) > 0 && !strings.EqualFold(rule.ToCategory, finding.Metadata[agent.MetadataToCategory]) {
+		return false
+	}
+	if rule.SameLabel {
+		fromLabel, toLabel := finding.Metadata[agent.MetadataFromLabel], finding.Metadata[agent.MetadataToLabel]
+		if len(fromLabel) == 0 || fromLabel != toLabel {
+			return false
+		}
+	}
+	return true
+}
+
+// IgnoreList is a list of ignore rules.
+type IgnoreList struct {
+	Rules []*IgnoreRule
+}
+
+// LoadIgnoreList reads the ignore rules from a JSON file.
+func LoadIgnoreList(path string) (*IgnoreList, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rules []*IgnoreRule
+	if err := json.Unmarshal(b, &rules); err != nil {
+		return nil, fmt.Errorf("failed to decode the ignore rules: %v", err)
+	}
+	for _, rule := range rules {
+		if err := rule.validate(); err != nil {
+			return nil, err
+		}
+	}
+	return &IgnoreList{Rules: rules}, nil
+}
+
+// Match returns the first rule which matches the finding. It should be called after
+// the labels are attached to the finding.
+func (il *IgnoreList) Match(finding *agent.Finding) (*IgnoreRule, bool) {
+	if il == nil {
+		return nil, false
+	}
+	for _, rule := range il.Rules {
+		if rule.matches(finding) {
+			return rule, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,140 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/canercidam/large-tx-detector/core/agent"
+	"github.com/canercidam/large-tx-detector/format"
+)
+
+// Discord embed limits
+const (
+	discordTitleLimit       = 256
+	discordDescriptionLimit = 4096
+	discordFieldCountLimit  = 25
+	discordFieldNameLimit   = 256
+	discordFieldValueLimit  = 1024
+	discordEmbedLimit       = 6000
+)
+
+var discordColors = map[agent.Severity]int{
+	agent.SeverityInfo:     0x3498db,
+	agent.SeverityLow:      0x2ecc71,
+	agent.SeverityMedium:   0xf1c40f,
+	agent.SeverityHigh:     0xe67e22,
+	agent.SeverityCritical: 0xe74c3c,
+}
+
+// DiscordConfig contains the Discord notifier config parameters.
+type DiscordConfig struct {
+	WebhookURL string      `json:"webhookUrl"`
+	Username   string      `json:"username"`
+	Retry      RetryConfig `json:"retry"`
+	// Templates replace the default embed description if there is one for Discord.
+	Templates *format.Templates `json:"-"`
+
+	// Client is used for the requests if specified.
+	Client *http.Client `json:"-"`
+}
+
+// DiscordNotifier posts the findings as embeds to a Discord webhook.
+type DiscordNotifier struct {
+	config  *DiscordConfig
+	retryer *retryer
+}
+
+type discordMessage struct {
+	Username string          `json:"username,omitempty"`
+	Embeds   []*discordEmbed `json:"embeds"`
+}
+
+type discordEmbed struct {
+	Title       string          `json:"title"`
+	Description string          `json:"description,omitempty"`
+	URL         string          `json:"url,omitempty"`
+	Color       int             `json:"color,omitempty"`
+	Fields      []*discordField `json:"fields,omitempty"`
+	Timestamp   string          `json:"timestamp,omitempty"`
+}
+
+type discordField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+// NewDiscordNotifier creates a new Discord notifier.
+func NewDiscordNotifier(conf *DiscordConfig) (*DiscordNotifier, error) {
+	if len(conf.WebhookURL) == 0 {
+		return nil, errors.New("discord webhook url is required")
+	}
+	dn := &DiscordNotifier{config: conf, retryer: newRetryer(conf.Client, conf.Retry)}
+	dn.retryer.retryAfter = discordRetryAfter
+	return dn, nil
+}
+
+// Notify posts the finding to the Discord channel.
+func (dn *DiscordNotifier) Notify(ctx context.Context, finding *agent.Finding) error {
+	body, err := json.Marshal(&discordMessage{
+		Username: dn.config.Username,
+		Embeds:   []*discordEmbed{dn.makeEmbed(finding)},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode the discord message: %v", err)
+	}
+	return dn.retryer.do(ctx, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, dn.config.WebhookURL, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
+	})
+}
+
+func (dn *DiscordNotifier) makeEmbed(finding *agent.Finding) *discordEmbed {
+	description := renderFinding(dn.config.Templates, DestinationDiscord, finding, findingSource)
+	embed := &discordEmbed{
+		Title:       truncate(findingTitle(finding), discordTitleLimit),
+		Description: truncate(description, discordDescriptionLimit),
+		URL:         format.FindingURL(finding),
+		Color:       discordColors[finding.Severity],
+		Timestamp:   time.Now().UTC().Format(time.RFC3339),
+	}
+	total := len(embed.Title) + len(embed.Description)
+	for _, f := range findingFields(finding) {
+		if len(embed.Fields) == discordFieldCountLimit {
+			break
+		}
+		df := &discordField{
+			Name:   truncate(f.Title, discordFieldNameLimit),
+			Value:  truncate(f.Text(), discordFieldValueLimit),
+			Inline: len(f.Value) <= 42, // Addresses fit side by side.
+		}
+		// Drop the fields which do not fit into the total embed size.
+		if total+len(df.Name)+len(df.Value) > discordEmbedLimit {
+			break
+		}
+		total += len(df.Name) + len(df.Value)
+		embed.Fields = append(embed.Fields, df)
+	}
+	return embed
+}
+
+// discordRetryAfter reads the wait time from the rate limit response body,
+// which is more precise than the header.
+func discordRetryAfter(resp *http.Response, body []byte) time.Duration {
+	var rateLimit struct {
+		RetryAfter float64 `json:"retry_after"`
+	}
+	if err := json.Unmarshal(body, &rateLimit); err != nil || rateLimit.RetryAfter <= 0 {
+		return retryAfterHeader(resp, body)
+	}
+	return time.Duration(rateLimit.RetryAfter * float64(time.Second))
+}
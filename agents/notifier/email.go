@@ -0,0 +1,309 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/ioutil"
+	"log"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/canercidam/large-tx-detector/core/agent"
+	"github.com/canercidam/large-tx-detector/format"
+)
+
+// Config vars
+var (
+	DefaultSMTPTimeout        = time.Second * 10
+	DefaultSMTPSessionTimeout = time.Minute
+)
+
+// Digest modes
+const (
+	DigestNone   = ""
+	DigestHourly = "hourly"
+	DigestDaily  = "daily"
+)
+
+const defaultEmailTextTemplate = `{{range .Findings}}{{title .}}
+Link: {{findingURL .}}
+Block: {{.BlockNumber}}
+{{range fields .}}{{.Title}}: {{.Text}}
+{{end}}
+{{end}}`
+
+const defaultEmailHTMLTemplate = `<html><body>
+{{range .Findings}}<h3>{{title .}}</h3>
+<p><a href="{{findingURL .}}">{{findingRef .}}</a> at block {{.BlockNumber}}</p>
+<table>{{range fields .}}<tr><th align="left">{{.Title}}</th><td>{{.Text}}</td></tr>{{end}}</table>
+{{end}}</body></html>`
+
+// EmailConfig contains the email notifier config parameters.
+type EmailConfig struct {
+	Host     string   `json:"host"`
+	Port     int      `json:"port"`
+	Username string   `json:"username"`
+	Password string   `json:"password"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+	// RequireTLS fails the delivery if the server does not support STARTTLS.
+	RequireTLS         bool `json:"requireTls"`
+	InsecureSkipVerify bool `json:"insecureSkipVerify"`
+	// Digest sends the findings once an hour or a day instead of one email per finding.
+	Digest string `json:"digest"`
+	// MaxBufferSize limits the findings kept for the digest. The oldest findings are dropped.
+	MaxBufferSize int `json:"maxBufferSize"`
+	// The templates are executed with the list of findings. The defaults are used if not specified.
+	TextTemplatePath string `json:"textTemplatePath"`
+	HTMLTemplatePath string `json:"htmlTemplatePath"`
+}
+
+// EmailNotifier sends the findings with SMTP.
+type EmailNotifier struct {
+	config       *EmailConfig
+	textTemplate *texttemplate.Template
+	htmlTemplate *htmltemplate.Template
+	buf          []*agent.Finding
+	mu           sync.Mutex
+}
+
+type emailData struct {
+	Digest   string
+	Findings []*agent.Finding
+}
+
+// NewEmailNotifier creates a new email notifier. In the digest mode, the
+// buffered findings are sent periodically until the context is done.
+func NewEmailNotifier(ctx context.Context, conf *EmailConfig) (*EmailNotifier, error) {
+	if len(conf.Host) == 0 || len(conf.From) == 0 || len(conf.To) == 0 {
+		return nil, errors.New("smtp host, sender and recipients are required")
+	}
+	if conf.Port == 0 {
+		conf.Port = 587
+	}
+	if conf.MaxBufferSize == 0 {
+		conf.MaxBufferSize = 1000
+	}
+
+	en := &EmailNotifier{config: conf}
+	funcs := format.Funcs()
+	funcs["title"] = findingTitle
+	funcs["fields"] = findingFields
+	textSrc, err := readTemplate(conf.TextTemplatePath, defaultEmailTextTemplate)
+	if err != nil {
+		return nil, err
+	}
+	if en.textTemplate, err = texttemplate.New("text").Funcs(funcs).Parse(textSrc); err != nil {
+		return nil, fmt.Errorf("failed to parse the text template: %v", err)
+	}
+	htmlSrc, err := readTemplate(conf.HTMLTemplatePath, defaultEmailHTMLTemplate)
+	if err != nil {
+		return nil, err
+	}
+	if en.htmlTemplate, err = htmltemplate.New("html").Funcs(funcs).Parse(htmlSrc); err != nil {
+		return nil, fmt.Errorf("failed to parse the html template: %v", err)
+	}
+
+	switch conf.Digest {
+	case DigestNone:
+	case DigestHourly:
+		go en.loop(ctx, time.Hour)
+	case DigestDaily:
+		go en.loop(ctx, time.Hour*24)
+	default:
+		return nil, fmt.Errorf("unknown digest mode '%s'", conf.Digest)
+	}
+
+	return en, nil
+}
+
+func readTemplate(path, defaultTemplate string) (string, error) {
+	if len(path) == 0 {
+		return defaultTemplate, nil
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the template: %v", err)
+	}
+	return string(b), nil
+}
+
+// Notify sends the finding or buffers it for the next digest.
+func (en *EmailNotifier) Notify(ctx context.Context, finding *agent.Finding) error {
+	if en.config.Digest == DigestNone {
+		return en.send([]*agent.Finding{finding})
+	}
+	en.mu.Lock()
+	defer en.mu.Unlock()
+	en.buf = append(en.buf, finding)
+	en.trimBuffer()
+	return nil
+}
+
+// trimBuffer drops the oldest findings which do not fit into the buffer.
+func (en *EmailNotifier) trimBuffer() {
+	overflow := len(en.buf) - en.config.MaxBufferSize
+	if overflow <= 0 {
+		return
+	}
+	log.Printf("email digest buffer is full: dropping the oldest %d findings", overflow)
+	en.buf = en.buf[overflow:]
+}
+
+func (en *EmailNotifier) loop(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			// Do not lose the last digest when shutting down.
+			en.sendDigest()
+			return
+		case <-ticker.C:
+			en.sendDigest()
+		}
+	}
+}
+
+func (en *EmailNotifier) sendDigest() {
+	en.mu.Lock()
+	findings := en.buf
+	en.buf = nil
+	en.mu.Unlock()
+
+	if len(findings) == 0 {
+		return
+	}
+	if err := en.send(findings); err != nil {
+		log.Printf("failed to send the email digest of %d findings: %v", len(findings), err)
+		// Put the findings back in front of the new ones to send them with the next digest.
+		en.mu.Lock()
+		en.buf = append(findings, en.buf...)
+		en.trimBuffer()
+		en.mu.Unlock()
+	}
+}
+
+func (en *EmailNotifier) send(findings []*agent.Finding) error {
+	msg, err := en.makeMessage(findings)
+	if err != nil {
+		return err
+	}
+	return en.deliver(msg)
+}
+
+func (en *EmailNotifier) subject(findings []*agent.Finding) string {
+	if en.config.Digest != DigestNone {
+		return fmt.Sprintf("%s digest: %d findings", en.config.Digest, len(findings))
+	}
+	return findingTitle(findings[0])
+}
+
+// makeMessage makes a multipart message with the plain text and the HTML alternatives.
+func (en *EmailNotifier) makeMessage(findings []*agent.Finding) ([]byte, error) {
+	data := &emailData{Digest: en.config.Digest, Findings: findings}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	parts := []struct {
+		contentType string
+		execute     func(w *quotedprintable.Writer) error
+	}{
+		{"text/plain", func(w *quotedprintable.Writer) error { return en.textTemplate.Execute(w, data) }},
+		{"text/html", func(w *quotedprintable.Writer) error { return en.htmlTemplate.Execute(w, data) }},
+	}
+	for _, part := range parts {
+		pw, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType + "; charset=UTF-8"},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qw := quotedprintable.NewWriter(pw)
+		if err := part.execute(qw); err != nil {
+			return nil, fmt.Errorf("failed to execute the %s template: %v", part.contentType, err)
+		}
+		qw.Close()
+	}
+	mw.Close()
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", en.config.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(en.config.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", en.subject(findings))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", mw.Boundary())
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
+
+// deliver sends the message using STARTTLS and authentication when available. The whole session
+// has a deadline so that a stalled server does not block the notifier.
+func (en *EmailNotifier) deliver(msg []byte) error {
+	addr := net.JoinHostPort(en.config.Host, strconv.Itoa(en.config.Port))
+	conn, err := net.DialTimeout("tcp", addr, DefaultSMTPTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to connect to the smtp server: %v", err)
+	}
+	if err := conn.SetDeadline(time.Now().Add(DefaultSMTPSessionTimeout)); err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to set the smtp deadline: %v", err)
+	}
+	c, err := smtp.NewClient(conn, en.config.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{
+			ServerName:         en.config.Host,
+			InsecureSkipVerify: en.config.InsecureSkipVerify,
+		}); err != nil {
+			return fmt.Errorf("failed to start tls: %v", err)
+		}
+	} else if en.config.RequireTLS {
+		return errors.New("smtp server does not support starttls")
+	}
+
+	if len(en.config.Username) > 0 {
+		if err := c.Auth(smtp.PlainAuth("", en.config.Username, en.config.Password, en.config.Host)); err != nil {
+			return fmt.Errorf("failed to authenticate: %v", err)
+		}
+	}
+
+	if err := c.Mail(en.config.From); err != nil {
+		return err
+	}
+	for _, to := range en.config.To {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
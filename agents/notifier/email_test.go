@@ -0,0 +1,241 @@
+package notifier
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/canercidam/large-tx-detector/core/agent"
+)
+
+// smtpStub is a minimal SMTP server which records the session.
+type smtpStub struct {
+	listener net.Listener
+	mu       sync.Mutex
+	commands []string
+	data     string
+	// stall keeps the connections open without greeting.
+	stall bool
+}
+
+func newSMTPStub(t *testing.T, stall bool) *smtpStub {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stub := &smtpStub{listener: listener, stall: stall}
+	t.Cleanup(func() { listener.Close() })
+	go stub.serve()
+	return stub
+}
+
+func (s *smtpStub) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *smtpStub) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *smtpStub) handle(conn net.Conn) {
+	defer conn.Close()
+	if s.stall {
+		conn.SetReadDeadline(time.Now().Add(time.Second * 5))
+		conn.Read(make([]byte, 1))
+		return
+	}
+	r := bufio.NewReader(conn)
+	reply := func(line string) { fmt.Fprintf(conn, "%s\r\n", line) }
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		s.mu.Lock()
+		s.commands = append(s.commands, line)
+		s.mu.Unlock()
+
+		switch cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0]); cmd {
+		case "EHLO", "HELO":
+			reply("250 localhost")
+		case "MAIL", "RCPT", "RSET", "NOOP":
+			reply("250 OK")
+		case "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				dataLine, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				data.WriteString(dataLine)
+			}
+			s.mu.Lock()
+			s.data = data.String()
+			s.mu.Unlock()
+			reply("250 OK")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+func emailTestFinding() *agent.Finding {
+	return &agent.Finding{
+		AgentID:     "default-agent",
+		TxHash:      "0xabc",
+		BlockNumber: 100,
+		Metadata: map[string]string{
+			agent.MetadataFrom:   "0x1111111111111111111111111111111111111111",
+			agent.MetadataTo:     "0x2222222222222222222222222222222222222222",
+			agent.MetadataAmount: "1000",
+		},
+	}
+}
+
+func TestEmailNotifierDelivers(t *testing.T) {
+	stub := newSMTPStub(t, false)
+	en, err := NewEmailNotifier(context.Background(), &EmailConfig{
+		Host: "127.0.0.1",
+		Port: stub.port(),
+		From: "alerts@example.com",
+		To:   []string{"a@example.com", "b@example.com"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := en.Notify(context.Background(), emailTestFinding()); err != nil {
+		t.Fatal(err)
+	}
+
+	stub.mu.Lock()
+	defer stub.mu.Unlock()
+	commands := strings.Join(stub.commands, "\n")
+	for _, expected := range []string{
+		"MAIL FROM:<alerts@example.com>", "RCPT TO:<a@example.com>", "RCPT TO:<b@example.com>", "DATA", "QUIT",
+	} {
+		if !strings.Contains(commands, expected) {
+			t.Errorf("expected the command '%s' in:\n%s", expected, commands)
+		}
+	}
+	if !strings.Contains(stub.data, "Subject:") || !strings.Contains(stub.data, "0xabc") {
+		t.Errorf("unexpected message:\n%s", stub.data)
+	}
+}
+
+func TestEmailNotifierRequiresTLS(t *testing.T) {
+	stub := newSMTPStub(t, false)
+	en, err := NewEmailNotifier(context.Background(), &EmailConfig{
+		Host:       "127.0.0.1",
+		Port:       stub.port(),
+		From:       "alerts@example.com",
+		To:         []string{"a@example.com"},
+		RequireTLS: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := en.Notify(context.Background(), emailTestFinding()); err == nil {
+		t.Fatal("expected an error without starttls")
+	}
+}
+
+func TestEmailNotifierTimesOutStalledServer(t *testing.T) {
+	defer func(timeout time.Duration) { DefaultSMTPSessionTimeout = timeout }(DefaultSMTPSessionTimeout)
+	DefaultSMTPSessionTimeout = time.Millisecond * 100
+
+	stub := newSMTPStub(t, true)
+	en, err := NewEmailNotifier(context.Background(), &EmailConfig{
+		Host: "127.0.0.1",
+		Port: stub.port(),
+		From: "alerts@example.com",
+		To:   []string{"a@example.com"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if err := en.Notify(context.Background(), emailTestFinding()); err == nil {
+		t.Fatal("expected a timeout error")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second*2 {
+		t.Fatalf("expected the session deadline to stop the delivery, took %s", elapsed)
+	}
+}
+
+func TestEmailDigestKeepsFindingsOnFailure(t *testing.T) {
+	// Nothing listens on the port of a closed listener.
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedPort := closed.Addr().(*net.TCPAddr).Port
+	closed.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	en, err := NewEmailNotifier(ctx, &EmailConfig{
+		Host:          "127.0.0.1",
+		Port:          closedPort,
+		From:          "alerts@example.com",
+		To:            []string{"a@example.com"},
+		Digest:        DigestHourly,
+		MaxBufferSize: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, txHash := range []string{"0xtx1", "0xtx2"} {
+		finding := emailTestFinding()
+		finding.TxHash = txHash
+		en.Notify(ctx, finding)
+	}
+	en.sendDigest()
+
+	en.mu.Lock()
+	kept := len(en.buf)
+	en.mu.Unlock()
+	if kept != 2 {
+		t.Fatalf("expected the failed digest to be kept, got %d findings", kept)
+	}
+
+	// The oldest finding is dropped when the buffer is full.
+	finding := emailTestFinding()
+	finding.TxHash = "0xtx3"
+	en.Notify(ctx, finding)
+
+	stub := newSMTPStub(t, false)
+	en.config.Port = stub.port()
+	en.sendDigest()
+
+	stub.mu.Lock()
+	data := stub.data
+	stub.mu.Unlock()
+	if strings.Contains(data, "0xtx1") || !strings.Contains(data, "0xtx2") || !strings.Contains(data, "0xtx3") {
+		t.Fatalf("expected the digest of the kept findings, got:\n%s", data)
+	}
+	en.mu.Lock()
+	defer en.mu.Unlock()
+	if len(en.buf) != 0 {
+		t.Fatalf("expected the buffer to be empty, got %d findings", len(en.buf))
+	}
+}
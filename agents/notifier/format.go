@@ -0,0 +1,117 @@
+package notifier
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/canercidam/large-tx-detector/core/agent"
+	"github.com/canercidam/large-tx-detector/format"
+)
+
+// field is a titled value to render. Addresses can have labels.
+type field struct {
+	Title string
+	Value string
+	Label string
+}
+
+// Text returns the value with the label.
+func (f field) Text() string {
+	if len(f.Label) == 0 {
+		return f.Value
+	}
+	return fmt.Sprintf("%s (%s)", f.Value, f.Label)
+}
+
+// addressLabels maps the address keys to their label and category keys.
+var addressLabels = map[string][2]string{
+	agent.MetadataFrom: {agent.MetadataFromLabel, agent.MetadataFromCategory},
+	agent.MetadataTo:   {agent.MetadataToLabel, agent.MetadataToCategory},
+}
+
+// findingTitle makes a short title for the finding.
+func findingTitle(finding *agent.Finding) string {
+	return fmt.Sprintf("[%s] %s", strings.ToUpper(string(finding.Severity)), finding.AlertType)
+}
+
+// findingSource tells which agent detected the finding and where.
+func findingSource(finding *agent.Finding) string {
+	return fmt.Sprintf("Detected by %s at block %d", finding.AgentID, finding.BlockNumber)
+}
+
+// findingFields turns the finding metadata into fields. The amount is formatted
+// and merged with the symbol, the labels are attached to the addresses and the raw amount is left out.
+func findingFields(finding *agent.Finding) []field {
+	var fields []field
+	for _, key := range finding.MetadataKeys() {
+		f := field{Title: metadataTitle(key), Value: finding.Metadata[key]}
+		switch key {
+		case agent.MetadataSymbol, agent.MetadataAmountRaw, agent.MetadataDecimals,
+			agent.MetadataFromLabel, agent.MetadataToLabel, agent.MetadataFromCategory, agent.MetadataToCategory:
+			continue
+		case agent.MetadataAmount:
+			f.Value = format.Amount(finding)
+		case agent.MetadataAmountUSD:
+			f.Value = format.USD(f.Value)
+		case agent.MetadataFrom, agent.MetadataTo:
+			f.Label = labelText(finding.Metadata[addressLabels[key][0]], finding.Metadata[addressLabels[key][1]])
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+func labelText(label, category string) string {
+	if len(label) == 0 || len(category) == 0 {
+		return label
+	}
+	return fmt.Sprintf("%s, %s", label, category)
+}
+
+// renderFinding renders the finding with the user defined template of the
+// notifier. The default format is used if there is no template or it fails.
+func renderFinding(templates *format.Templates, notifier string, finding *agent.Finding, defaultFormat func(*agent.Finding) string) string {
+	text, ok, err := templates.Render(notifier, finding)
+	if err != nil {
+		log.Printf("failed to render finding of tx %s: %v", finding.TxHash, err)
+	}
+	if !ok || err != nil {
+		return defaultFormat(finding)
+	}
+	return text
+}
+
+var metadataTitles = map[string]string{
+	agent.MetadataAmountUSD: "USD Value",
+}
+
+// metadataTitle turns a camel case metadata key into a readable title.
+func metadataTitle(key string) string {
+	if title, ok := metadataTitles[key]; ok {
+		return title
+	}
+	var b strings.Builder
+	for i, r := range key {
+		switch {
+		case i == 0:
+			b.WriteString(strings.ToUpper(string(r)))
+		case r >= 'A' && r <= 'Z':
+			b.WriteRune(' ')
+			b.WriteRune(r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// truncate shortens the string to the max number of characters.
+func truncate(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:max-1]) + "…"
+}
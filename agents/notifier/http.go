@@ -0,0 +1,181 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Config vars
+var (
+	DefaultHTTPTimeout    = time.Second * 10
+	DefaultMaxRetries     = 5
+	DefaultInitialBackoff = time.Millisecond * 500
+	DefaultMaxBackoff     = time.Second * 30
+	DefaultMaxElapsed     = time.Minute * 2
+)
+
+const maxErrorBodySize = 1024
+
+// RetryConfig contains the retry parameters of the HTTP based notifiers.
+type RetryConfig struct {
+	MaxRetries       int `json:"maxRetries"`
+	InitialBackoffMs int `json:"initialBackoffMs"`
+	MaxBackoffMs     int `json:"maxBackoffMs"`
+	// MaxElapsedMs bounds the total time of a request with the retries.
+	MaxElapsedMs int `json:"maxElapsedMs"`
+}
+
+// statusError is returned when the response status is not 2xx.
+type statusError struct {
+	StatusCode int
+	Body       string
+	RetryAfter time.Duration
+}
+
+func (se *statusError) Error() string {
+	return fmt.Sprintf("unexpected status %d: %s", se.StatusCode, se.Body)
+}
+
+// temporary tells if the request can succeed when retried. Client errors
+// other than rate limiting are not retried since they fail the same way every time.
+func (se *statusError) temporary() bool {
+	return se.StatusCode == http.StatusTooManyRequests ||
+		se.StatusCode == http.StatusRequestTimeout ||
+		se.StatusCode >= 500
+}
+
+// retryError is returned when the retries are given up.
+type retryError struct {
+	Attempts int
+	Reason   string
+	Err      error
+}
+
+func (re *retryError) Error() string {
+	return fmt.Sprintf("giving up after %d attempts%s: %v", re.Attempts, re.Reason, re.Err)
+}
+
+// lastStatusError returns the status error of the last attempt, if any.
+func lastStatusError(err error) *statusError {
+	if re, ok := err.(*retryError); ok {
+		err = re.Err
+	}
+	se, _ := err.(*statusError)
+	return se
+}
+
+// retryer does HTTP requests with exponential backoff retries.
+type retryer struct {
+	client         *http.Client
+	maxRetries     int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	maxElapsed     time.Duration
+	// retryAfter finds out how long to wait from a rate limited response.
+	retryAfter func(resp *http.Response, body []byte) time.Duration
+}
+
+func newRetryer(client *http.Client, conf RetryConfig) *retryer {
+	r := &retryer{
+		client:         client,
+		maxRetries:     conf.MaxRetries,
+		initialBackoff: time.Duration(conf.InitialBackoffMs) * time.Millisecond,
+		maxBackoff:     time.Duration(conf.MaxBackoffMs) * time.Millisecond,
+		maxElapsed:     time.Duration(conf.MaxElapsedMs) * time.Millisecond,
+		retryAfter:     retryAfterHeader,
+	}
+	if r.client == nil {
+		r.client = &http.Client{Timeout: DefaultHTTPTimeout}
+	}
+	if r.maxRetries == 0 {
+		r.maxRetries = DefaultMaxRetries
+	}
+	if r.initialBackoff == 0 {
+		r.initialBackoff = DefaultInitialBackoff
+	}
+	if r.maxBackoff == 0 {
+		r.maxBackoff = DefaultMaxBackoff
+	}
+	if r.maxElapsed == 0 {
+		r.maxElapsed = DefaultMaxElapsed
+	}
+	return r
+}
+
+// do sends the request made by newReq until it succeeds, fails permanently, the retries are
+// exhausted or maxElapsed has passed. The server supplied waits are respected and the request
+// fails at once if the wait does not fit in maxElapsed, since the notifiers can block the block
+// processing. The request is made again for each attempt so that the body can be reread.
+func (r *retryer) do(ctx context.Context, newReq func(context.Context) (*http.Request, error)) (err error) {
+	deadline := time.Now().Add(r.maxElapsed)
+	backoff := r.initialBackoff
+	for attempt := 0; ; attempt++ {
+		var wait time.Duration
+		wait, err = r.try(ctx, newReq)
+		if err == nil {
+			return nil
+		}
+		if se, ok := err.(*statusError); ok && !se.temporary() {
+			return err
+		}
+		if attempt >= r.maxRetries {
+			return &retryError{Attempts: attempt + 1, Err: err}
+		}
+
+		if wait > 0 && time.Now().Add(wait).After(deadline) {
+			return &retryError{Attempts: attempt + 1, Reason: fmt.Sprintf(", server asked to retry after %s", wait), Err: err}
+		}
+		if wait == 0 {
+			wait = backoff
+			backoff *= 2
+			if backoff > r.maxBackoff {
+				backoff = r.maxBackoff
+			}
+		}
+		if time.Now().Add(wait).After(deadline) {
+			return &retryError{Attempts: attempt + 1, Reason: fmt.Sprintf(" in %s", r.maxElapsed), Err: err}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// try sends the request once and returns how long to wait before retrying, if the server has told so.
+func (r *retryer) try(ctx context.Context, newReq func(context.Context) (*http.Request, error)) (time.Duration, error) {
+	req, err := newReq(ctx)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return 0, nil
+	}
+
+	se := &statusError{StatusCode: resp.StatusCode, Body: string(body)}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		se.RetryAfter = r.retryAfter(resp, body)
+	}
+	return se.RetryAfter, se
+}
+
+// retryAfterHeader reads the standard Retry-After header in seconds.
+func retryAfterHeader(resp *http.Response, body []byte) time.Duration {
+	seconds, err := strconv.ParseFloat(resp.Header.Get("Retry-After"), 64)
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
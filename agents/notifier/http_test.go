@@ -0,0 +1,145 @@
+package notifier
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// statusServer replies with the statuses in order and then with 200.
+func statusServer(t *testing.T, header http.Header, statuses ...int) (*httptest.Server, *int32) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(&calls, 1))
+		if n > len(statuses) {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		for key, values := range header {
+			w.Header()[key] = values
+		}
+		w.WriteHeader(statuses[n-1])
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func getRequest(url string) func(context.Context) (*http.Request, error) {
+	return func(ctx context.Context) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	}
+}
+
+func testRetryer(conf RetryConfig) *retryer {
+	if conf.InitialBackoffMs == 0 {
+		conf.InitialBackoffMs = 1
+	}
+	if conf.MaxBackoffMs == 0 {
+		conf.MaxBackoffMs = 5
+	}
+	return newRetryer(nil, conf)
+}
+
+func TestRetryerDoesNotRetryClientErrors(t *testing.T) {
+	srv, calls := statusServer(t, nil, http.StatusBadRequest)
+	err := testRetryer(RetryConfig{}).do(context.Background(), getRequest(srv.URL))
+	se, ok := err.(*statusError)
+	if !ok || se.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected the 400 error, got %v", err)
+	}
+	if n := atomic.LoadInt32(calls); n != 1 {
+		t.Fatalf("expected 1 attempt, got %d", n)
+	}
+}
+
+func TestRetryerRetriesServerErrors(t *testing.T) {
+	srv, calls := statusServer(t, nil, http.StatusInternalServerError, http.StatusBadGateway)
+	if err := testRetryer(RetryConfig{}).do(context.Background(), getRequest(srv.URL)); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(calls); n != 3 {
+		t.Fatalf("expected 3 attempts, got %d", n)
+	}
+}
+
+func TestRetryerGivesUpAfterMaxRetries(t *testing.T) {
+	srv, calls := statusServer(t, nil,
+		http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable,
+	)
+	if err := testRetryer(RetryConfig{MaxRetries: 2}).do(context.Background(), getRequest(srv.URL)); err == nil {
+		t.Fatal("expected an error")
+	}
+	if n := atomic.LoadInt32(calls); n != 3 {
+		t.Fatalf("expected 3 attempts, got %d", n)
+	}
+}
+
+func TestRetryerWaitsForRetryAfter(t *testing.T) {
+	header := http.Header{"Retry-After": []string{"0.2"}}
+	srv, calls := statusServer(t, header, http.StatusTooManyRequests)
+	start := time.Now()
+	if err := testRetryer(RetryConfig{}).do(context.Background(), getRequest(srv.URL)); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Millisecond*200 {
+		t.Fatalf("expected to wait as long as the server asked, took %s", elapsed)
+	}
+	if n := atomic.LoadInt32(calls); n != 2 {
+		t.Fatalf("expected 2 attempts, got %d", n)
+	}
+}
+
+func TestRetryerGivesUpOnLongRetryAfter(t *testing.T) {
+	header := http.Header{"Retry-After": []string{"3600"}}
+	srv, calls := statusServer(t, header, http.StatusTooManyRequests)
+	start := time.Now()
+	err := testRetryer(RetryConfig{}).do(context.Background(), getRequest(srv.URL))
+	if err == nil || !strings.Contains(err.Error(), "1h0m0s") {
+		t.Fatalf("expected an error with the retry after, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected to give up at once, took %s", elapsed)
+	}
+	if n := atomic.LoadInt32(calls); n != 1 {
+		t.Fatalf("expected 1 attempt, got %d", n)
+	}
+}
+
+func TestRetryerBoundsTotalTime(t *testing.T) {
+	statuses := make([]int, 10)
+	for i := range statuses {
+		statuses[i] = http.StatusServiceUnavailable
+	}
+	srv, calls := statusServer(t, nil, statuses...)
+	r := testRetryer(RetryConfig{MaxRetries: 100, InitialBackoffMs: 40, MaxBackoffMs: 50, MaxElapsedMs: 120})
+	start := time.Now()
+	if err := r.do(context.Background(), getRequest(srv.URL)); err == nil {
+		t.Fatal("expected an error")
+	}
+	if elapsed := time.Since(start); elapsed > time.Millisecond*500 {
+		t.Fatalf("expected to give up within the max elapsed time, took %s", elapsed)
+	}
+	if n := atomic.LoadInt32(calls); n != 3 {
+		t.Fatalf("expected 3 attempts, got %d", n)
+	}
+}
+
+func TestRetryAfterHeader(t *testing.T) {
+	tests := map[string]time.Duration{
+		"":                              0,
+		"2":                             time.Second * 2,
+		"0.5":                           time.Millisecond * 500,
+		"-1":                            0,
+		"Wed, 21 Oct 2015 07:28:00 GMT": 0,
+	}
+	for value, expected := range tests {
+		resp := &http.Response{Header: http.Header{"Retry-After": []string{value}}}
+		if wait := retryAfterHeader(resp, nil); wait != expected {
+			t.Errorf("Retry-After '%s': expected %s, got %s", value, expected, wait)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/canercidam/large-tx-detector/core/agent"
+	"github.com/canercidam/large-tx-detector/format"
+)
+
+// LogConfig contains the log notifier config parameters.
+type LogConfig struct {
+	// Path is the file which the findings are appended to. The standard output is used when empty.
+	Path string `json:"path"`
+	// Templates replace the default log line if there is one for the log notifier.
+	Templates *format.Templates `json:"-"`
+}
+
+// LogNotifier writes the findings to a log file.
+type LogNotifier struct {
+	config *LogConfig
+	logger *log.Logger
+}
+
+// NewLogNotifier creates a new log notifier.
+func NewLogNotifier(conf *LogConfig) (*LogNotifier, error) {
+	if len(conf.Path) == 0 {
+		return &LogNotifier{config: conf, logger: log.New(os.Stdout, "", log.LstdFlags)}, nil
+	}
+	f, err := os.OpenFile(conf.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the log file: %v", err)
+	}
+	return &LogNotifier{config: conf, logger: log.New(f, "", log.LstdFlags)}, nil
+}
+
+// Notify writes the finding as a single log line.
+func (ln *LogNotifier) Notify(ctx context.Context, finding *agent.Finding) error {
+	ln.logger.Print(renderFinding(ln.config.Templates, DestinationLog, finding, formatLogLine))
+	return nil
+}
+
+func formatLogLine(finding *agent.Finding) string {
+	fields := []string{
+		fmt.Sprintf("agent=%s", finding.AgentID),
+		fmt.Sprintf("severity=%s", finding.Severity),
+		fmt.Sprintf("block=%d", finding.BlockNumber),
+		fmt.Sprintf("tx=%s", finding.TxHash),
+	}
+	for _, key := range finding.MetadataKeys() {
+		fields = append(fields, fmt.Sprintf("%s=%s", key, finding.Metadata[key]))
+	}
+	return fmt.Sprintf("finding: %s %s", finding.AlertType, strings.Join(fields, " "))
+}
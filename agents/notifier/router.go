@@ -0,0 +1,299 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"strings"
+
+	"github.com/canercidam/large-tx-detector/core/agent"
+	"github.com/canercidam/large-tx-detector/format"
+)
+
+// Destination types
+const (
+	DestinationSlack    = "slack"
+	DestinationLog      = "log"
+	DestinationWebhook  = "webhook"
+	DestinationDiscord  = "discord"
+	DestinationTeams    = "teams"
+	DestinationTelegram = "telegram"
+	DestinationEmail    = "email"
+)
+
+// RouterConfig contains the notification destinations and the rules to route the findings.
+type RouterConfig struct {
+	Destinations map[string]*DestinationConfig `json:"destinations"`
+	Rules        []*RouteRule                  `json:"rules"`
+	// Default destinations receive the findings which did not match any rule.
+	Default []string `json:"default"`
+
+	SlackThreads agent.ThreadRepository `json:"-"`
+	Templates    *format.Templates      `json:"-"`
+}
+
+// DestinationConfig describes a named notifier. Options are decoded into
+// the config of the notifier type.
+type DestinationConfig struct {
+	Type    string          `json:"type"`
+	Options json.RawMessage `json:"options"`
+}
+
+// RouteRule matches findings and sends them to the destinations. Empty
+// fields match everything.
+type RouteRule struct {
+	Name         string         `json:"name"`
+	AgentIDs     []string       `json:"agentIds"`
+	MinSeverity  agent.Severity `json:"minSeverity"`
+	Tokens       []string       `json:"tokens"`
+	Labels       []string       `json:"labels"`
+	MinAmount    string         `json:"minAmount"`
+	MaxAmount    string         `json:"maxAmount"`
+	Destinations []string       `json:"destinations"`
+	// Stop prevents the next rules from being evaluated if this rule matches.
+	Stop bool `json:"stop"`
+
+	minAmount *big.Rat
+	maxAmount *big.Rat
+}
+
+// LoadRouterConfig reads the router config from a JSON file.
+func LoadRouterConfig(path string) (*RouterConfig, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var conf RouterConfig
+	if err := json.Unmarshal(b, &conf); err != nil {
+		return nil, fmt.Errorf("failed to decode the router config: %v", err)
+	}
+	// An unknown severity ranks zero and would match every finding.
+	for i, rule := range conf.Rules {
+		if len(rule.MinSeverity) > 0 && rule.MinSeverity.Rank() == 0 {
+			return nil, fmt.Errorf("invalid rule %d (%s): unknown severity '%s'", i, rule.Name, rule.MinSeverity)
+		}
+	}
+	return &conf, nil
+}
+
+// Router sends each finding to the destinations of the matching rules.
+type Router struct {
+	conf         *RouterConfig
+	destinations map[string]agent.Notifier
+}
+
+// NewRouter creates a new router and the notifiers of the destinations.
+func NewRouter(ctx context.Context, conf *RouterConfig) (*Router, error) {
+	router := &Router{conf: conf, destinations: make(map[string]agent.Notifier)}
+	for name, destConf := range conf.Destinations {
+		notifier, err := router.newDestination(ctx, destConf)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create destination '%s': %v", name, err)
+		}
+		router.destinations[name] = notifier
+	}
+
+	for i, rule := range conf.Rules {
+		if err := router.checkDestinations(rule.Destinations); err != nil {
+			return nil, fmt.Errorf("invalid rule %d (%s): %v", i, rule.Name, err)
+		}
+		var err error
+		if rule.minAmount, err = parseAmount(rule.MinAmount); err != nil {
+			return nil, fmt.Errorf("invalid rule %d (%s): %v", i, rule.Name, err)
+		}
+		if rule.maxAmount, err = parseAmount(rule.MaxAmount); err != nil {
+			return nil, fmt.Errorf("invalid rule %d (%s): %v", i, rule.Name, err)
+		}
+	}
+	if err := router.checkDestinations(conf.Default); err != nil {
+		return nil, fmt.Errorf("invalid default destinations: %v", err)
+	}
+
+	return router, nil
+}
+
+func (router *Router) newDestination(ctx context.Context, destConf *DestinationConfig) (agent.Notifier, error) {
+	switch destConf.Type {
+	case DestinationSlack:
+		// The global Slack config is the default and the options can change the workspace and the channel.
+		slackConf := DefaultSlackConfig()
+		if err := decodeOptions(destConf.Options, slackConf); err != nil {
+			return nil, err
+		}
+		slackConf.Threads = router.conf.SlackThreads
+		slackConf.Templates = router.conf.Templates
+		return NewSlackNotifier(ctx, slackConf)
+
+	case DestinationLog:
+		var logConf LogConfig
+		if err := decodeOptions(destConf.Options, &logConf); err != nil {
+			return nil, err
+		}
+		logConf.Templates = router.conf.Templates
+		return NewLogNotifier(&logConf)
+
+	case DestinationWebhook:
+		var webhookConf WebhookConfig
+		if err := decodeOptions(destConf.Options, &webhookConf); err != nil {
+			return nil, err
+		}
+		return NewWebhookNotifier(&webhookConf)
+
+	case DestinationDiscord:
+		var discordConf DiscordConfig
+		if err := decodeOptions(destConf.Options, &discordConf); err != nil {
+			return nil, err
+		}
+		discordConf.Templates = router.conf.Templates
+		return NewDiscordNotifier(&discordConf)
+
+	case DestinationTeams:
+		var teamsConf TeamsConfig
+		if err := decodeOptions(destConf.Options, &teamsConf); err != nil {
+			return nil, err
+		}
+		teamsConf.Templates = router.conf.Templates
+		return NewTeamsNotifier(&teamsConf)
+
+	case DestinationTelegram:
+		var telegramConf TelegramConfig
+		if err := decodeOptions(destConf.Options, &telegramConf); err != nil {
+			return nil, err
+		}
+		telegramConf.Templates = router.conf.Templates
+		return NewTelegramNotifier(ctx, &telegramConf)
+
+	case DestinationEmail:
+		var emailConf EmailConfig
+		if err := decodeOptions(destConf.Options, &emailConf); err != nil {
+			return nil, err
+		}
+		return NewEmailNotifier(ctx, &emailConf)
+
+	default:
+		return nil, fmt.Errorf("unknown destination type '%s'", destConf.Type)
+	}
+}
+
+func decodeOptions(options json.RawMessage, v interface{}) error {
+	if len(options) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(options, v); err != nil {
+		return fmt.Errorf("failed to decode the options: %v", err)
+	}
+	return nil
+}
+
+func (router *Router) checkDestinations(names []string) error {
+	for _, name := range names {
+		if _, ok := router.destinations[name]; !ok {
+			return fmt.Errorf("unknown destination '%s'", name)
+		}
+	}
+	return nil
+}
+
+func parseAmount(amount string) (*big.Rat, error) {
+	if len(amount) == 0 {
+		return nil, nil
+	}
+	r, ok := big.NewRat(0, 1).SetString(amount)
+	if !ok {
+		return nil, fmt.Errorf("invalid amount '%s'", amount)
+	}
+	return r, nil
+}
+
+// Notify implements agent.Notifier. A failing destination does not prevent
+// the others from being notified and an error is returned only if all of them failed.
+func (router *Router) Notify(ctx context.Context, finding *agent.Finding) error {
+	names := router.route(finding)
+	if len(names) == 0 {
+		return nil
+	}
+
+	var failed int
+	for _, name := range names {
+		if err := router.destinations[name].Notify(ctx, finding); err != nil {
+			log.Printf("failed to notify destination '%s' about tx %s: %v", name, finding.TxHash, err)
+			failed++
+		}
+	}
+	if failed == len(names) {
+		return errors.New("failed to notify all destinations")
+	}
+	return nil
+}
+
+// route finds the names of the destinations for the finding.
+func (router *Router) route(finding *agent.Finding) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, rule := range router.conf.Rules {
+		if !rule.matches(finding) {
+			continue
+		}
+		for _, name := range rule.Destinations {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+		if rule.Stop {
+			break
+		}
+	}
+	if len(names) == 0 {
+		return router.conf.Default
+	}
+	return names
+}
+
+func (rule *RouteRule) matches(finding *agent.Finding) bool {
+	if len(rule.AgentIDs) > 0 && !containsFold(rule.AgentIDs, finding.AgentID) {
+		return false
+	}
+	if len(rule.MinSeverity) > 0 && finding.Severity.Rank() < rule.MinSeverity.Rank() {
+		return false
+	}
+	if len(rule.Tokens) > 0 &&
+		!containsFold(rule.Tokens, finding.Metadata[agent.MetadataSymbol]) &&
+		!containsFold(rule.Tokens, finding.Metadata[agent.MetadataToken]) {
+		return false
+	}
+	if len(rule.Labels) > 0 &&
+		!containsFold(rule.Labels, finding.Metadata[agent.MetadataFromLabel]) &&
+		!containsFold(rule.Labels, finding.Metadata[agent.MetadataToLabel]) {
+		return false
+	}
+	if rule.minAmount != nil || rule.maxAmount != nil {
+		amount, err := parseAmount(finding.Metadata[agent.MetadataAmount])
+		if err != nil || amount == nil {
+			return false
+		}
+		if rule.minAmount != nil && amount.Cmp(rule.minAmount) < 0 {
+			return false
+		}
+		if rule.maxAmount != nil && amount.Cmp(rule.maxAmount) >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func containsFold(list []string, s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/canercidam/large-tx-detector/core/agent"
+)
+
+// recordingNotifier records the notified findings and fails if err is set.
+type recordingNotifier struct {
+	findings []*agent.Finding
+	err      error
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, finding *agent.Finding) error {
+	n.findings = append(n.findings, finding)
+	return n.err
+}
+
+func routerTestFinding(agentID string, severity agent.Severity) *agent.Finding {
+	return &agent.Finding{AgentID: agentID, Severity: severity, TxHash: "0xabc", Metadata: map[string]string{}}
+}
+
+func TestRouterMatchesSeverityAndAgent(t *testing.T) {
+	pager, logfile := &recordingNotifier{}, &recordingNotifier{}
+	router := &Router{
+		conf: &RouterConfig{
+			Rules: []*RouteRule{
+				{Name: "critical", AgentIDs: []string{"supply-agent"}, MinSeverity: agent.SeverityHigh, Destinations: []string{"pager"}},
+			},
+			Default: []string{"logfile"},
+		},
+		destinations: map[string]agent.Notifier{"pager": pager, "logfile": logfile},
+	}
+
+	tests := []struct {
+		finding *agent.Finding
+		paged   bool
+	}{
+		{finding: routerTestFinding("supply-agent", agent.SeverityCritical), paged: true},
+		{finding: routerTestFinding("SUPPLY-AGENT", agent.SeverityHigh), paged: true},
+		{finding: routerTestFinding("supply-agent", agent.SeverityMedium), paged: false},
+		{finding: routerTestFinding("supply-agent", ""), paged: false},
+		{finding: routerTestFinding("default-agent", agent.SeverityCritical), paged: false},
+	}
+	for i, test := range tests {
+		pager.findings, logfile.findings = nil, nil
+		if err := router.Notify(context.Background(), test.finding); err != nil {
+			t.Fatal(err)
+		}
+		if paged := len(pager.findings) == 1; paged != test.paged {
+			t.Errorf("%d: expected paged %v", i, test.paged)
+		}
+		if defaulted := len(logfile.findings) == 1; defaulted == test.paged {
+			t.Errorf("%d: expected the default destination only for the unmatched finding", i)
+		}
+	}
+}
+
+func TestRouterFailsOnlyWhenAllDestinationsFail(t *testing.T) {
+	failing := &recordingNotifier{err: errors.New("failed")}
+	working := &recordingNotifier{}
+	destinations := map[string]agent.Notifier{"failing": failing, "working": working, "other": &recordingNotifier{err: errors.New("failed")}}
+
+	router := &Router{conf: &RouterConfig{}, destinations: destinations}
+	router.conf.Default = []string{"failing", "working"}
+	if err := router.Notify(context.Background(), routerTestFinding("default-agent", agent.SeverityInfo)); err != nil {
+		t.Fatalf("expected no error when a destination works, got %v", err)
+	}
+	if len(failing.findings) != 1 || len(working.findings) != 1 {
+		t.Fatal("expected every destination to be notified")
+	}
+
+	router.conf.Default = []string{"failing", "other"}
+	if err := router.Notify(context.Background(), routerTestFinding("default-agent", agent.SeverityInfo)); err == nil {
+		t.Fatal("expected an error when all destinations fail")
+	}
+}
+
+func TestLoadRouterConfigRejectsUnknownSeverity(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "routes.json")
+	conf := `{"rules": [{"name": "pager", "minSeverity": "hihg", "destinations": []}]}`
+	if err := ioutil.WriteFile(path, []byte(conf), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := LoadRouterConfig(path)
+	if err == nil || !strings.Contains(err.Error(), "hihg") {
+		t.Fatalf("expected an unknown severity error, got %v", err)
+	}
+
+	conf = `{"rules": [{"name": "pager", "minSeverity": "high", "destinations": []}]}`
+	if err := ioutil.WriteFile(path, []byte(conf), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadRouterConfig(path); err != nil {
+		t.Fatal(err)
+	}
+}
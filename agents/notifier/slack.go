@@ -2,64 +2,333 @@ package notifier
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/canercidam/large-tx-detector/agents"
 	"github.com/canercidam/large-tx-detector/config"
+	"github.com/canercidam/large-tx-detector/core/agent"
+	"github.com/canercidam/large-tx-detector/format"
 	"github.com/slack-go/slack"
 )
 
-// SlackNotifier is a notifier implementation.
+// Slack message limits
+const (
+	slackBlockLimit      = 50
+	slackBlocksPerAlert  = 3
+	slackSectionLimit    = 3000
+	slackFieldCountLimit = 10
+	slackFieldLimit      = 2000
+	slackButtonLimit     = 75
+)
+
+// SlackConfig contains the Slack notifier config parameters.
+type SlackConfig struct {
+	Token                 string `json:"token"`
+	ChannelID             string `json:"channelId"`
+	NotifyIntervalSeconds int    `json:"notifyIntervalSeconds"`
+	MaxRetries            int    `json:"maxRetries"`
+	MaxBufferSize         int    `json:"maxBufferSize"`
+	// Interactive adds the action buttons to the alerts. It requires the interactivity endpoint.
+	Interactive bool `json:"interactive"`
+
+	// ThreadBy is the metadata key which relates the alerts, e.g. "from". The related alerts
+	// within the window are posted as replies to the first one. Threading is disabled if empty.
+	ThreadBy            string `json:"threadBy"`
+	ThreadWindowMinutes int    `json:"threadWindowMinutes"`
+	// ThreadBroadcast sends the replies also to the channel.
+	ThreadBroadcast bool                   `json:"threadBroadcast"`
+	Threads         agent.ThreadRepository `json:"-"`
+	// Templates replace the default section text if there is one for Slack.
+	Templates *format.Templates `json:"-"`
+
+	// APIURL is the Web API endpoint and can point to a local stub.
+	APIURL string `json:"apiUrl"`
+}
+
+// DefaultSlackConfig makes the Slack config from the environment.
+func DefaultSlackConfig() *SlackConfig {
+	return &SlackConfig{
+		Token:                 config.Vars.SlackOAuthToken,
+		ChannelID:             config.Vars.SlackChannelID,
+		NotifyIntervalSeconds: config.Vars.SlackNotifyIntervalSeconds,
+		MaxRetries:            config.Vars.SlackMaxRetries,
+		MaxBufferSize:         config.Vars.SlackMaxBufferSize,
+		Interactive:           len(config.Vars.SlackSigningSecret) > 0,
+		ThreadBy:              config.Vars.SlackThreadBy,
+		ThreadWindowMinutes:   config.Vars.SlackThreadWindowMinutes,
+		ThreadBroadcast:       config.Vars.SlackThreadBroadcast,
+	}
+}
+
+// SlackNotifier is a notifier implementation. The findings are buffered and
+// posted periodically. When the buffer is full, the oldest findings are dropped.
 type SlackNotifier struct {
+	config *SlackConfig
 	client *slack.Client
-	buf    []string
+	buf    []*agent.Finding
 	mu     sync.Mutex
 }
 
-// NewSlackNotifier creates a new Slack notifier.a
-func NewSlackNotifier() *SlackNotifier {
-	sn := &SlackNotifier{client: slack.New(config.Vars.SlackOAuthToken)}
-	go sn.loop()
-	return sn
+// NewSlackNotifier creates a new Slack notifier which posts the buffered findings until the context is done.
+func NewSlackNotifier(ctx context.Context, conf *SlackConfig) (*SlackNotifier, error) {
+	if len(conf.Token) == 0 || len(conf.ChannelID) == 0 {
+		return nil, errors.New("slack token and channel id are required")
+	}
+	if conf.NotifyIntervalSeconds == 0 {
+		conf.NotifyIntervalSeconds = 15
+	}
+	if conf.MaxBufferSize == 0 {
+		conf.MaxBufferSize = 500
+	}
+	if conf.ThreadWindowMinutes == 0 {
+		conf.ThreadWindowMinutes = 60
+	}
+	if len(conf.ThreadBy) > 0 && conf.Threads == nil {
+		return nil, errors.New("slack thread repository is required for threading")
+	}
+	var options []slack.Option
+	if len(conf.APIURL) > 0 {
+		options = append(options, slack.OptionAPIURL(conf.APIURL))
+	}
+	sn := &SlackNotifier{config: conf, client: slack.New(conf.Token, options...)}
+	go sn.loop(ctx)
+	return sn, nil
 }
 
 // Notify notifies a slack channel.
-func (sn *SlackNotifier) Notify(ctx context.Context, notif *agents.LargeTxNotification) error {
+func (sn *SlackNotifier) Notify(ctx context.Context, finding *agent.Finding) error {
 	sn.mu.Lock()
 	defer sn.mu.Unlock()
-	sn.buf = append(sn.buf, fmt.Sprintf(
-		`*Tx:* <%s/tx/%s|%s>
-*From:* %s
-*To:* %s
-*Amount:* %.2f %s`,
-		config.Vars.EtherscanBaseURL, notif.Hash, notif.Hash, notif.From, notif.To, notif.Value, notif.Symbol,
-	))
+	sn.buf = append(sn.buf, finding)
+	sn.trimBuffer()
 	return nil
 }
 
-func (sn *SlackNotifier) loop() {
-	ticker := time.NewTicker(time.Second * (time.Duration)(config.Vars.SlackNotifyIntervalSeconds))
-	for _ = range ticker.C {
-		sn.postBufferedMessages()
+// trimBuffer drops the oldest findings which do not fit into the buffer.
+func (sn *SlackNotifier) trimBuffer() {
+	overflow := len(sn.buf) - sn.config.MaxBufferSize
+	if overflow <= 0 {
+		return
+	}
+	log.Printf("slack buffer is full: dropping the oldest %d findings", overflow)
+	sn.buf = sn.buf[overflow:]
+}
+
+// makeBlocks makes a section with the finding fields, followed by the
+// explorer and the action buttons and a divider. A user defined template
+// replaces the whole section text.
+func (sn *SlackNotifier) makeBlocks(finding *agent.Finding) []slack.Block {
+	text, ok, err := sn.config.Templates.Render(DestinationSlack, finding)
+	if err != nil {
+		log.Printf("failed to render finding of tx %s: %v", finding.TxHash, err)
+	}
+	var fields []*slack.TextBlockObject
+	if !ok || err != nil {
+		text = fmt.Sprintf("*%s*\n<%s|%s>", findingTitle(finding), format.FindingURL(finding), format.FindingRef(finding))
+		fields = makeSlackFields(finding)
+	}
+	button := slack.NewButtonBlockElement("view-tx", "", slack.NewTextBlockObject(
+		slack.PlainTextType, truncate("View on explorer", slackButtonLimit), false, false,
+	))
+	button.URL = format.FindingURL(finding)
+	buttons := []slack.BlockElement{button}
+	if sn.config.Interactive {
+		buttons = append(buttons, makeSlackActionButtons(finding)...)
+	}
+
+	return []slack.Block{
+		slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, truncate(text, slackSectionLimit), false, false), fields, nil),
+		slack.NewActionBlock("", buttons...),
+		slack.NewDividerBlock(),
+	}
+}
+
+func makeSlackFields(finding *agent.Finding) []*slack.TextBlockObject {
+	var fields []*slack.TextBlockObject
+	for _, f := range findingFields(finding) {
+		if len(fields) == slackFieldCountLimit {
+			break
+		}
+		fields = append(fields, slack.NewTextBlockObject(
+			slack.MarkdownType, truncate(fmt.Sprintf("*%s:*\n%s", f.Title, f.Text()), slackFieldLimit), false, false,
+		))
+	}
+	return fields
+}
+
+// splitFindings splits the findings into chunks which fit into a single message.
+func splitFindings(findings []*agent.Finding) [][]*agent.Finding {
+	var chunks [][]*agent.Finding
+	size := slackBlockLimit / slackBlocksPerAlert
+	for len(findings) > size {
+		chunks = append(chunks, findings[:size])
+		findings = findings[size:]
+	}
+	if len(findings) > 0 {
+		chunks = append(chunks, findings)
+	}
+	return chunks
+}
+
+func (sn *SlackNotifier) loop(ctx context.Context) {
+	ticker := time.NewTicker(time.Second * (time.Duration)(sn.config.NotifyIntervalSeconds))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			sn.postBufferedMessages(ctx)
+		}
 	}
 }
 
-func (sn *SlackNotifier) postBufferedMessages() {
+// postBufferedMessages posts the buffered findings in chunks. The buffer is
+// not locked while posting so that the rate limit waits do not block the notifications.
+func (sn *SlackNotifier) postBufferedMessages(ctx context.Context) {
 	sn.mu.Lock()
-	defer sn.mu.Unlock()
-	if len(sn.buf) == 0 {
+	findings := sn.buf
+	sn.buf = nil
+	sn.mu.Unlock()
+
+	unsent := sn.postFindings(ctx, findings)
+	if len(unsent) == 0 {
 		return
 	}
-	_, _, err := sn.client.PostMessage(config.Vars.SlackChannelID, slack.MsgOptionText(
-		strings.Join(sn.buf, "\n\n"), false,
-	))
-	if err != nil {
+	// Put the unsent findings back in front of the new ones to try again at the next tick.
+	sn.mu.Lock()
+	sn.buf = append(unsent, sn.buf...)
+	sn.trimBuffer()
+	sn.mu.Unlock()
+}
+
+// postFindings posts the new alerts as top level messages first, each thread starter in its
+// own message, and then the related alerts as replies. It stops at the first temporary failure and returns the findings
+// which were not posted. The chunks which fail permanently are dropped so that they do not block the rest.
+func (sn *SlackNotifier) postFindings(ctx context.Context, findings []*agent.Finding) (unsent []*agent.Finding) {
+	group := sn.groupByThread(findings)
+
+	for _, chunk := range splitFindings(group.topLevel) {
+		if len(unsent) > 0 {
+			unsent = append(unsent, chunk...)
+			continue
+		}
+		if _, err := sn.postMessageWithRetry(ctx, "", chunk); err != nil {
+			unsent = append(unsent, sn.failedChunk(chunk, err)...)
+		}
+	}
+
+	for _, starter := range group.starters {
+		chunk := []*agent.Finding{starter}
+		if len(unsent) > 0 {
+			unsent = append(unsent, chunk...)
+			continue
+		}
+		ts, err := sn.postMessageWithRetry(ctx, "", chunk)
+		if err != nil {
+			unsent = append(unsent, sn.failedChunk(chunk, err)...)
+			continue
+		}
+		sn.saveThread(starter, ts, group.threads)
+	}
+
+	for _, key := range group.keys {
+		for _, chunk := range splitFindings(group.replies[key]) {
+			ts, ok := group.threads[key]
+			if len(unsent) > 0 || !ok {
+				unsent = append(unsent, chunk...)
+				continue
+			}
+			if _, err := sn.postMessageWithRetry(ctx, ts, chunk); err != nil {
+				unsent = append(unsent, sn.failedChunk(chunk, err)...)
+			}
+		}
+	}
+
+	return
+}
+
+// failedChunk logs the failure and returns the findings to try again, which is none if the failure is permanent.
+func (sn *SlackNotifier) failedChunk(chunk []*agent.Finding, err error) []*agent.Finding {
+	if isTemporarySlackError(err) {
 		log.Printf("failed to post the slack message: %v", err)
-		return
+		return chunk
 	}
-	sn.buf = nil
+	log.Printf("dropping %d findings after a permanent slack error: %v", len(chunk), err)
+	return nil
+}
+
+// isTemporarySlackError tells if posting again can succeed: the rate limits, the server
+// errors and the network errors are temporary.
+func isTemporarySlackError(err error) bool {
+	if err == context.Canceled || err == context.DeadlineExceeded {
+		return true
+	}
+	if retryable, ok := err.(interface{ Retryable() bool }); ok {
+		return retryable.Retryable()
+	}
+	if _, ok := err.(net.Error); ok {
+		return true
+	}
+	switch err.Error() {
+	case "internal_error", "fatal_error", "service_unavailable", "request_timeout", "ratelimited":
+		return true
+	}
+	return false
+}
+
+// postMessageWithRetry posts the chunk and retries the temporary errors, after the time Slack tells when rate limited.
+func (sn *SlackNotifier) postMessageWithRetry(ctx context.Context, threadTS string, findings []*agent.Finding) (string, error) {
+	backoff := DefaultInitialBackoff
+	for attempt := 0; ; attempt++ {
+		ts, err := sn.postMessage(ctx, threadTS, findings)
+		if err == nil {
+			return ts, nil
+		}
+		if attempt >= sn.config.MaxRetries || !isTemporarySlackError(err) {
+			return "", err
+		}
+
+		wait := backoff
+		backoff *= 2
+		if rateLimitErr, ok := err.(*slack.RateLimitedError); ok {
+			wait = rateLimitErr.RetryAfter
+		}
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// postMessage posts the findings as a single message and returns its timestamp.
+// The message is a reply if the thread timestamp is specified.
+func (sn *SlackNotifier) postMessage(ctx context.Context, threadTS string, findings []*agent.Finding) (string, error) {
+	var (
+		blocks []slack.Block
+		titles []string
+	)
+	for _, finding := range findings {
+		blocks = append(blocks, sn.makeBlocks(finding)...)
+		titles = append(titles, findingTitle(finding))
+	}
+	options := []slack.MsgOption{
+		slack.MsgOptionBlocks(blocks...),
+		// The text is shown in the notifications.
+		slack.MsgOptionText(truncate(strings.Join(titles, ", "), slackSectionLimit), false),
+	}
+	if len(threadTS) > 0 {
+		options = append(options, slack.MsgOptionTS(threadTS))
+		if sn.config.ThreadBroadcast {
+			options = append(options, slack.MsgOptionBroadcast())
+		}
+	}
+	_, ts, err := sn.client.PostMessageContext(ctx, sn.config.ChannelID, options...)
+	return ts, err
 }
@@ -0,0 +1,238 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/canercidam/large-tx-detector/core/agent"
+	"github.com/slack-go/slack"
+)
+
+// Config vars
+var (
+	MuteDuration       = time.Hour * 24
+	SlackRequestMaxAge = time.Minute * 5
+)
+
+// Slack action IDs
+const (
+	slackActionAck       = "ack"
+	slackActionMute      = "mute"
+	slackActionMinAmount = "min-amount"
+)
+
+const maxSlackRequestSize = 1 << 20
+
+// slackActionValue is the button value which identifies the finding.
+type slackActionValue struct {
+	AgentID string `json:"agentId"`
+	TxHash  string `json:"txHash"`
+	Subject string `json:"subject"`
+	Address string `json:"address"`
+	Amount  string `json:"amount"`
+}
+
+// makeSlackActionButtons makes the buttons to act on the finding.
+func makeSlackActionButtons(finding *agent.Finding) []slack.BlockElement {
+	b, _ := json.Marshal(&slackActionValue{
+		AgentID: finding.AgentID,
+		TxHash:  finding.TxHash,
+		Subject: agent.AckSubject(finding),
+		Address: finding.Metadata[agent.MetadataFrom],
+		Amount:  finding.Metadata[agent.MetadataAmount],
+	})
+	value := string(b)
+	var buttons []slack.BlockElement
+	if len(agent.AckSubject(finding)) > 0 {
+		buttons = append(buttons, slack.NewButtonBlockElement(slackActionAck, value, slack.NewTextBlockObject(
+			slack.PlainTextType, "Ack", false, false,
+		)))
+	}
+	if len(finding.Metadata[agent.MetadataFrom]) > 0 {
+		buttons = append(buttons, slack.NewButtonBlockElement(slackActionMute, value, slack.NewTextBlockObject(
+			slack.PlainTextType, fmt.Sprintf("Mute sender for %s", humanDuration(MuteDuration)), false, false,
+		)))
+	}
+	if len(finding.Metadata[agent.MetadataAmount]) > 0 {
+		buttons = append(buttons, slack.NewButtonBlockElement(slackActionMinAmount, value, slack.NewTextBlockObject(
+			slack.PlainTextType, "Ignore transfers below this", false, false,
+		)))
+	}
+	return buttons
+}
+
+// SlackActionHandler handles the clicks on the alert buttons and turns them into actions.
+type SlackActionHandler struct {
+	signingSecret string
+	actions       agent.ActionRepository
+	client        *http.Client
+	now           func() time.Time
+}
+
+// NewSlackActionHandler creates a new handler for the Slack interactivity requests.
+func NewSlackActionHandler(signingSecret string, actions agent.ActionRepository) *SlackActionHandler {
+	return &SlackActionHandler{
+		signingSecret: signingSecret,
+		actions:       actions,
+		client:        &http.Client{Timeout: DefaultHTTPTimeout},
+		now:           time.Now,
+	}
+}
+
+// ServeHTTP implements http.Handler.
+func (h *SlackActionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxSlackRequestSize))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if err := h.verify(r.Header, body); err != nil {
+		log.Printf("rejected slack action request: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	var callback slack.InteractionCallback
+	if err := json.Unmarshal([]byte(form.Get("payload")), &callback); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if callback.Type != slack.InteractionTypeBlockActions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	var replies []string
+	for _, action := range callback.ActionCallback.BlockActions {
+		reply, err := h.handleAction(action, callback.User.Name)
+		if err != nil {
+			log.Printf("failed to handle slack action '%s': %v", action.ActionID, err)
+			reply = fmt.Sprintf("Failed to handle the action: %v", err)
+		}
+		if len(reply) > 0 {
+			replies = append(replies, reply)
+		}
+	}
+	// Slack expects the response within 3 seconds, so the replies are sent afterwards.
+	w.WriteHeader(http.StatusOK)
+	if len(replies) > 0 && len(callback.ResponseURL) > 0 {
+		go h.respondAll(callback.ResponseURL, replies)
+	}
+}
+
+func (h *SlackActionHandler) respondAll(responseURL string, replies []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultHTTPTimeout)
+	defer cancel()
+	for _, reply := range replies {
+		h.respond(ctx, responseURL, reply)
+	}
+}
+
+// verify checks the request signature with the signing secret.
+// See https://api.slack.com/authentication/verifying-requests-from-slack
+func (h *SlackActionHandler) verify(header http.Header, body []byte) error {
+	timestamp := header.Get("X-Slack-Request-Timestamp")
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.New("invalid timestamp")
+	}
+	age := h.now().Sub(time.Unix(seconds, 0))
+	if age > SlackRequestMaxAge || age < -SlackRequestMaxAge {
+		return errors.New("request is too old")
+	}
+
+	mac := hmac.New(sha256.New, []byte(h.signingSecret))
+	fmt.Fprintf(mac, "v0:%s:", timestamp)
+	mac.Write(body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(header.Get("X-Slack-Signature"))) {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+// handleAction saves the action and returns the reply to the user.
+func (h *SlackActionHandler) handleAction(action *slack.BlockAction, user string) (string, error) {
+	var value slackActionValue
+	switch action.ActionID {
+	case slackActionAck, slackActionMute, slackActionMinAmount:
+		if err := json.Unmarshal([]byte(action.Value), &value); err != nil {
+			return "", fmt.Errorf("invalid action value: %v", err)
+		}
+	default:
+		// Probably a link button.
+		return "", nil
+	}
+
+	switch action.ActionID {
+	case slackActionAck:
+		err := h.actions.SaveAck(&agent.Ack{
+			AgentID: value.AgentID, Subject: value.Subject, TxHash: value.TxHash, By: user, At: h.now(),
+		})
+		return fmt.Sprintf("%s acknowledged the %s alerts about %s", user, value.AgentID, value.Subject), err
+
+	case slackActionMute:
+		until := h.now().Add(MuteDuration)
+		err := h.actions.SaveMute(&agent.Mute{Address: value.Address, Until: until, By: user})
+		return fmt.Sprintf("%s muted %s until %s", user, value.Address, until.UTC().Format(time.RFC1123)), err
+
+	default:
+		err := h.actions.SaveMinAmount(&agent.MinAmount{AgentID: value.AgentID, Amount: value.Amount, By: user})
+		return fmt.Sprintf("%s set %s to ignore the transfers below %s", user, value.AgentID, value.Amount), err
+	}
+}
+
+// respond sends a message which only the user who clicked the button can see.
+func (h *SlackActionHandler) respond(ctx context.Context, responseURL, text string) {
+	b, _ := json.Marshal(map[string]interface{}{
+		"response_type":    "ephemeral",
+		"replace_original": false,
+		"text":             text,
+	})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, responseURL, bytes.NewReader(b))
+	if err != nil {
+		log.Printf("failed to make the slack response: %v", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := h.client.Do(req)
+	if err != nil {
+		log.Printf("failed to send the slack response: %v", err)
+		return
+	}
+	resp.Body.Close()
+}
+
+// humanDuration formats the duration like "24 hours" or "30 minutes".
+func humanDuration(d time.Duration) string {
+	unit, name := time.Minute, "minute"
+	if d%time.Hour == 0 {
+		unit, name = time.Hour, "hour"
+	}
+	n := int64(d / unit)
+	if n == 1 {
+		return fmt.Sprintf("1 %s", name)
+	}
+	return fmt.Sprintf("%d %ss", n, name)
+}
@@ -0,0 +1,223 @@
+package notifier
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/canercidam/large-tx-detector/core/agent"
+	"github.com/canercidam/large-tx-detector/repository/badgerrepo"
+	"github.com/slack-go/slack"
+)
+
+const testSigningSecret = "secret"
+
+func actionTestFinding() *agent.Finding {
+	return &agent.Finding{
+		AgentID: "default-agent",
+		TxHash:  "0xabc",
+		Metadata: map[string]string{
+			agent.MetadataFrom:   "0xFrom",
+			agent.MetadataTo:     "0xTo",
+			agent.MetadataAmount: "1000.5",
+		},
+	}
+}
+
+func newTestActionHandler(t *testing.T) (*SlackActionHandler, *badgerrepo.Repository) {
+	repo, err := badgerrepo.New("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { repo.Close() })
+	return NewSlackActionHandler(testSigningSecret, repo), repo
+}
+
+// actionRequestBody makes the interaction payload of clicking the rendered button of the finding.
+func actionRequestBody(t *testing.T, finding *agent.Finding, actionID, responseURL string) string {
+	var button *slack.ButtonBlockElement
+	for _, element := range makeSlackActionButtons(finding) {
+		if b, ok := element.(*slack.ButtonBlockElement); ok && b.ActionID == actionID {
+			button = b
+		}
+	}
+	if button == nil {
+		t.Fatalf("no '%s' button", actionID)
+	}
+	payload, err := json.Marshal(map[string]interface{}{
+		"type":         "block_actions",
+		"user":         map[string]string{"name": "alice"},
+		"response_url": responseURL,
+		"actions": []map[string]string{
+			{"type": "button", "block_id": "actions", "action_id": button.ActionID, "value": button.Value},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return url.Values{"payload": []string{string(payload)}}.Encode()
+}
+
+func signedActionRequest(body string, timestamp time.Time, secret string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/slack/actions", strings.NewReader(body))
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "v0:%s:%s", ts, body)
+	req.Header.Set("X-Slack-Request-Timestamp", ts)
+	req.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+	return req
+}
+
+func TestSlackActionHandlerVerifiesRequests(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name      string
+		timestamp time.Time
+		secret    string
+		status    int
+	}{
+		{name: "valid", timestamp: now, secret: testSigningSecret, status: http.StatusOK},
+		{name: "invalid signature", timestamp: now, secret: "other", status: http.StatusUnauthorized},
+		{name: "old request", timestamp: now.Add(-SlackRequestMaxAge * 2), secret: testSigningSecret, status: http.StatusUnauthorized},
+		{name: "future request", timestamp: now.Add(SlackRequestMaxAge * 2), secret: testSigningSecret, status: http.StatusUnauthorized},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h, repo := newTestActionHandler(t)
+			h.now = func() time.Time { return now }
+
+			finding := actionTestFinding()
+			body := actionRequestBody(t, finding, slackActionAck, "")
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, signedActionRequest(body, test.timestamp, test.secret))
+
+			if rec.Code != test.status {
+				t.Fatalf("expected status %d, got %d", test.status, rec.Code)
+			}
+			ack, err := repo.GetAck(finding.AgentID, agent.AckSubject(finding))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.status == http.StatusOK && (ack == nil || ack.By != "alice" || ack.TxHash != "0xabc") {
+				t.Fatalf("expected the ack to be saved, got %+v", ack)
+			}
+			if test.status != http.StatusOK && ack != nil {
+				t.Fatal("expected no ack")
+			}
+		})
+	}
+}
+
+func TestSlackActionHandlerRequiresTimestamp(t *testing.T) {
+	h, _ := newTestActionHandler(t)
+	req := signedActionRequest(actionRequestBody(t, actionTestFinding(), slackActionAck, ""), time.Now(), testSigningSecret)
+	req.Header.Del("X-Slack-Request-Timestamp")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status %d, got %d", http.StatusUnauthorized, rec.Code)
+	}
+}
+
+func TestSlackActionHandlerSavesMute(t *testing.T) {
+	h, repo := newTestActionHandler(t)
+	now := time.Now()
+	h.now = func() time.Time { return now }
+
+	body := actionRequestBody(t, actionTestFinding(), slackActionMute, "")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, signedActionRequest(body, now, testSigningSecret))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d", rec.Code)
+	}
+	mute, err := repo.GetMute("0xFrom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mute == nil || mute.By != "alice" || !mute.Until.Equal(now.Add(MuteDuration)) {
+		t.Fatalf("expected the sender to be muted, got %+v", mute)
+	}
+}
+
+func TestSlackActionHandlerSavesMinAmount(t *testing.T) {
+	h, repo := newTestActionHandler(t)
+	body := actionRequestBody(t, actionTestFinding(), slackActionMinAmount, "")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, signedActionRequest(body, time.Now(), testSigningSecret))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d", rec.Code)
+	}
+	minAmount, err := repo.GetMinAmount("default-agent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if minAmount == nil || minAmount.Amount != "1000.5" || minAmount.By != "alice" {
+		t.Fatalf("expected the min amount to be saved, got %+v", minAmount)
+	}
+}
+
+func TestSlackActionHandlerRespondsAfterAck(t *testing.T) {
+	release := make(chan struct{})
+	replies := make(chan string, 1)
+	responseSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		var msg struct {
+			Text string `json:"text"`
+		}
+		json.NewDecoder(r.Body).Decode(&msg)
+		replies <- msg.Text
+	}))
+	defer responseSrv.Close()
+
+	h, _ := newTestActionHandler(t)
+	body := actionRequestBody(t, actionTestFinding(), slackActionAck, responseSrv.URL)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, signedActionRequest(body, time.Now(), testSigningSecret))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d", rec.Code)
+	}
+
+	// The handler has returned while the response URL is still blocked.
+	close(release)
+	select {
+	case reply := <-replies:
+		if !strings.Contains(reply, "alice acknowledged") {
+			t.Fatalf("unexpected reply '%s'", reply)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("expected the reply")
+	}
+}
+
+func TestSlackActionButtons(t *testing.T) {
+	buttons := makeSlackActionButtons(actionTestFinding())
+	if len(buttons) != 3 {
+		t.Fatalf("expected 3 buttons, got %d", len(buttons))
+	}
+	mute := buttons[1].(*slack.ButtonBlockElement)
+	if mute.Text.Text != "Mute sender for 24 hours" {
+		t.Fatalf("unexpected mute button text '%s'", mute.Text.Text)
+	}
+}
+
+func TestHumanDuration(t *testing.T) {
+	tests := map[time.Duration]string{
+		time.Hour * 24:   "24 hours",
+		time.Hour:        "1 hour",
+		time.Minute * 90: "90 minutes",
+	}
+	for d, expected := range tests {
+		if s := humanDuration(d); s != expected {
+			t.Errorf("%s: expected '%s', got '%s'", d, expected, s)
+		}
+	}
+}
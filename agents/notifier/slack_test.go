@@ -0,0 +1,153 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/canercidam/large-tx-detector/core/agent"
+	"github.com/canercidam/large-tx-detector/repository/badgerrepo"
+)
+
+// slackStub replies to chat.postMessage with the handler of the request number.
+func slackStub(t *testing.T, reply func(n int, w http.ResponseWriter)) (*httptest.Server, *int32) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat.postMessage" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		reply(int(atomic.AddInt32(&calls, 1)), w)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func newTestSlackNotifier(t *testing.T, apiURL string) *SlackNotifier {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	sn, err := NewSlackNotifier(ctx, &SlackConfig{
+		Token:                 "token",
+		ChannelID:             "C1",
+		NotifyIntervalSeconds: 3600,
+		APIURL:                apiURL + "/",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sn
+}
+
+// twoChunks makes enough findings for two messages.
+func twoChunks() []*agent.Finding {
+	var findings []*agent.Finding
+	for i := 0; i <= slackBlockLimit/slackBlocksPerAlert; i++ {
+		findings = append(findings, &agent.Finding{AgentID: "default-agent", TxHash: fmt.Sprintf("0x%d", i)})
+	}
+	return findings
+}
+
+func TestSlackDropsPermanentFailures(t *testing.T) {
+	srv, calls := slackStub(t, func(n int, w http.ResponseWriter) {
+		if n == 1 {
+			fmt.Fprint(w, `{"ok":false,"error":"invalid_blocks"}`)
+			return
+		}
+		fmt.Fprint(w, `{"ok":true,"channel":"C1","ts":"1.0"}`)
+	})
+	sn := newTestSlackNotifier(t, srv.URL)
+	sn.config.MaxRetries = 3
+
+	if unsent := sn.postFindings(context.Background(), twoChunks()); len(unsent) != 0 {
+		t.Fatalf("expected the failed chunk to be dropped, got %d unsent", len(unsent))
+	}
+	if n := atomic.LoadInt32(calls); n != 2 {
+		t.Fatalf("expected 2 requests without retrying the permanent error, got %d", n)
+	}
+}
+
+func TestSlackKeepsTemporaryFailures(t *testing.T) {
+	srv, calls := slackStub(t, func(n int, w http.ResponseWriter) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	sn := newTestSlackNotifier(t, srv.URL)
+
+	findings := twoChunks()
+	if unsent := sn.postFindings(context.Background(), findings); len(unsent) != len(findings) {
+		t.Fatalf("expected all findings to be kept, got %d unsent", len(unsent))
+	}
+	if n := atomic.LoadInt32(calls); n != 1 {
+		t.Fatalf("expected to stop at the first failure, got %d requests", n)
+	}
+}
+
+func TestIsTemporarySlackError(t *testing.T) {
+	for _, test := range []struct {
+		err       error
+		temporary bool
+	}{
+		{err: fmt.Errorf("channel_not_found"), temporary: false},
+		{err: fmt.Errorf("invalid_auth"), temporary: false},
+		{err: fmt.Errorf("internal_error"), temporary: true},
+		{err: context.DeadlineExceeded, temporary: true},
+	} {
+		if temporary := isTemporarySlackError(test.err); temporary != test.temporary {
+			t.Errorf("%v: expected temporary %v", test.err, test.temporary)
+		}
+	}
+}
+
+func TestSlackThreadsOnlyTheStartingKey(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		posts []url.Values
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		mu.Lock()
+		posts = append(posts, r.PostForm)
+		n := len(posts)
+		mu.Unlock()
+		fmt.Fprintf(w, `{"ok":true,"channel":"C1","ts":"%d.0"}`, n)
+	}))
+	defer srv.Close()
+	repo, err := badgerrepo.New("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close()
+
+	sn := newTestSlackNotifier(t, srv.URL)
+	sn.config.ThreadBy = agent.MetadataFrom
+	sn.config.Threads = repo
+
+	finding := func(txHash, from string) *agent.Finding {
+		return &agent.Finding{AgentID: "default-agent", TxHash: txHash, Metadata: map[string]string{agent.MetadataFrom: from}}
+	}
+	findings := []*agent.Finding{finding("0x1", "0xA"), finding("0x2", "0xB"), finding("0x3", "0xA")}
+	if unsent := sn.postFindings(context.Background(), findings); len(unsent) != 0 {
+		t.Fatalf("expected all findings to be posted, got %d unsent", len(unsent))
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(posts) != 3 {
+		t.Fatalf("expected 3 messages, got %d", len(posts))
+	}
+	if posts[0].Get("thread_ts") != "" || posts[1].Get("thread_ts") != "" {
+		t.Fatal("expected the thread starters to be posted to the channel")
+	}
+	if posts[2].Get("thread_ts") != "1.0" {
+		t.Fatalf("expected the reply in the thread of 0xA, got '%s'", posts[2].Get("thread_ts"))
+	}
+	for key, ts := range map[string]string{"0xA": "1.0", "0xB": "2.0"} {
+		thread, err := repo.GetSlackThread("C1", key)
+		if err != nil || thread == nil || thread.TS != ts {
+			t.Fatalf("expected the thread %s of %s, got %+v (%v)", ts, key, thread, err)
+		}
+	}
+}
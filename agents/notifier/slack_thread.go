@@ -0,0 +1,69 @@
+package notifier
+
+import (
+	"log"
+	"time"
+
+	"github.com/canercidam/large-tx-detector/core/agent"
+)
+
+// threadGroup separates the findings which start new threads from the replies.
+type threadGroup struct {
+	// topLevel are the findings without a thread key.
+	topLevel []*agent.Finding
+	// starters are the first findings of the keys without a thread, which are posted one by one.
+	starters []*agent.Finding
+	replies  map[string][]*agent.Finding
+	// keys keep the order of the replies.
+	keys []string
+	// threads are the timestamps of the thread messages by key.
+	threads map[string]string
+}
+
+// groupByThread finds out which findings should be posted as replies. The first finding
+// of a key without a thread starts the thread and the rest wait for the thread to be created.
+func (sn *SlackNotifier) groupByThread(findings []*agent.Finding) *threadGroup {
+	group := &threadGroup{replies: make(map[string][]*agent.Finding), threads: make(map[string]string)}
+	if len(sn.config.ThreadBy) == 0 {
+		group.topLevel = findings
+		return group
+	}
+
+	starting := make(map[string]bool)
+	for _, finding := range findings {
+		key := finding.Metadata[sn.config.ThreadBy]
+		if len(key) == 0 {
+			group.topLevel = append(group.topLevel, finding)
+			continue
+		}
+		if _, ok := group.threads[key]; !ok && !starting[key] {
+			thread, err := sn.config.Threads.GetSlackThread(sn.config.ChannelID, key)
+			if err != nil {
+				log.Printf("failed to get the slack thread for %s: %v", key, err)
+			}
+			if thread != nil {
+				group.threads[key] = thread.TS
+			} else {
+				starting[key] = true
+				group.starters = append(group.starters, finding)
+				continue
+			}
+		}
+		if len(group.replies[key]) == 0 {
+			group.keys = append(group.keys, key)
+		}
+		group.replies[key] = append(group.replies[key], finding)
+	}
+	return group
+}
+
+// saveThread remembers the posted message as the thread of the finding which started it.
+func (sn *SlackNotifier) saveThread(starter *agent.Finding, ts string, threads map[string]string) {
+	key := starter.Metadata[sn.config.ThreadBy]
+	threads[key] = ts
+	ttl := time.Minute * time.Duration(sn.config.ThreadWindowMinutes)
+	thread := &agent.SlackThread{ChannelID: sn.config.ChannelID, Key: key, TS: ts}
+	if err := sn.config.Threads.SaveSlackThread(thread, ttl); err != nil {
+		log.Printf("failed to save the slack thread for %s: %v", key, err)
+	}
+}
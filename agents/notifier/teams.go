@@ -0,0 +1,153 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/canercidam/large-tx-detector/core/agent"
+	"github.com/canercidam/large-tx-detector/format"
+)
+
+// Teams message limits
+const (
+	teamsMessageLimit = 28 * 1024
+	teamsTextLimit    = 1024
+)
+
+var teamsColors = map[agent.Severity]string{
+	agent.SeverityInfo:     "accent",
+	agent.SeverityLow:      "good",
+	agent.SeverityMedium:   "warning",
+	agent.SeverityHigh:     "warning",
+	agent.SeverityCritical: "attention",
+}
+
+// TeamsConfig contains the Microsoft Teams notifier config parameters.
+type TeamsConfig struct {
+	WebhookURL string      `json:"webhookUrl"`
+	Retry      RetryConfig `json:"retry"`
+	// Templates replace the default card text if there is one for Teams.
+	Templates *format.Templates `json:"-"`
+
+	// Client is used for the requests if specified.
+	Client *http.Client `json:"-"`
+}
+
+// TeamsNotifier posts the findings as adaptive cards to a Teams webhook.
+type TeamsNotifier struct {
+	config  *TeamsConfig
+	retryer *retryer
+}
+
+type teamsMessage struct {
+	Type        string             `json:"type"`
+	Attachments []*teamsAttachment `json:"attachments"`
+}
+
+type teamsAttachment struct {
+	ContentType string             `json:"contentType"`
+	Content     *teamsAdaptiveCard `json:"content"`
+}
+
+type teamsAdaptiveCard struct {
+	Schema  string                   `json:"$schema"`
+	Type    string                   `json:"type"`
+	Version string                   `json:"version"`
+	Body    []map[string]interface{} `json:"body"`
+	Actions []map[string]interface{} `json:"actions,omitempty"`
+}
+
+type teamsFact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+// NewTeamsNotifier creates a new Teams notifier.
+func NewTeamsNotifier(conf *TeamsConfig) (*TeamsNotifier, error) {
+	if len(conf.WebhookURL) == 0 {
+		return nil, errors.New("teams webhook url is required")
+	}
+	return &TeamsNotifier{config: conf, retryer: newRetryer(conf.Client, conf.Retry)}, nil
+}
+
+// Notify posts the finding to the Teams channel.
+func (tn *TeamsNotifier) Notify(ctx context.Context, finding *agent.Finding) error {
+	body, err := makeTeamsMessage(finding, renderFinding(tn.config.Templates, DestinationTeams, finding, findingSource))
+	if err != nil {
+		return fmt.Errorf("failed to encode the teams message: %v", err)
+	}
+	return tn.retryer.do(ctx, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, tn.config.WebhookURL, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
+	})
+}
+
+// makeTeamsMessage encodes the finding as an adaptive card and drops the
+// facts from the end until the message fits into the size limit.
+func makeTeamsMessage(finding *agent.Finding, text string) ([]byte, error) {
+	var facts []*teamsFact
+	for _, f := range findingFields(finding) {
+		facts = append(facts, &teamsFact{Title: truncate(f.Title, teamsTextLimit), Value: truncate(f.Text(), teamsTextLimit)})
+	}
+	for {
+		body, err := json.Marshal(makeTeamsCard(finding, text, facts))
+		if err != nil {
+			return nil, err
+		}
+		if len(body) <= teamsMessageLimit || len(facts) == 0 {
+			return body, nil
+		}
+		facts = facts[:len(facts)-1]
+	}
+}
+
+func makeTeamsCard(finding *agent.Finding, text string, facts []*teamsFact) *teamsMessage {
+	return &teamsMessage{
+		Type: "message",
+		Attachments: []*teamsAttachment{
+			{
+				ContentType: "application/vnd.microsoft.card.adaptive",
+				Content: &teamsAdaptiveCard{
+					Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+					Type:    "AdaptiveCard",
+					Version: "1.2",
+					Body: []map[string]interface{}{
+						{
+							"type":   "TextBlock",
+							"text":   truncate(findingTitle(finding), teamsTextLimit),
+							"weight": "Bolder",
+							"size":   "Medium",
+							"color":  teamsColors[finding.Severity],
+							"wrap":   true,
+						},
+						{
+							"type":     "TextBlock",
+							"text":     truncate(text, teamsTextLimit),
+							"isSubtle": true,
+							"wrap":     true,
+						},
+						{
+							"type":  "FactSet",
+							"facts": facts,
+						},
+					},
+					Actions: []map[string]interface{}{
+						{
+							"type":  "Action.OpenUrl",
+							"title": "View transaction",
+							"url":   format.FindingURL(finding),
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,263 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/canercidam/large-tx-detector/core/agent"
+	"github.com/canercidam/large-tx-detector/format"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Config vars
+var (
+	DefaultTelegramAPIURL = "https://api.telegram.org"
+)
+
+const (
+	telegramMessageLimit = 4096
+	telegramValueLimit   = 256
+)
+
+// telegramURLEscaper escapes the characters which MarkdownV2 does not allow in the link URLs.
+var telegramURLEscaper = strings.NewReplacer(`\`, `\\`, ")", `\)`)
+
+// TelegramConfig contains the Telegram notifier config parameters.
+type TelegramConfig struct {
+	BotToken              string      `json:"botToken"`
+	ChatID                string      `json:"chatId"`
+	NotifyIntervalSeconds int         `json:"notifyIntervalSeconds"`
+	MaxBufferSize         int         `json:"maxBufferSize"`
+	Retry                 RetryConfig `json:"retry"`
+	// Templates replace the default message if there is one for Telegram.
+	// The templates must escape the MarkdownV2 special characters with the markdownV2 helper.
+	Templates *format.Templates `json:"-"`
+
+	// APIURL is the Bot API endpoint and can point to a local stub.
+	APIURL string `json:"apiUrl"`
+	// Client is used for the requests if specified.
+	Client *http.Client `json:"-"`
+}
+
+// TelegramNotifier sends the findings to a Telegram chat in batches.
+type TelegramNotifier struct {
+	config  *TelegramConfig
+	retryer *retryer
+	buf     []string
+	mu      sync.Mutex
+	// pausedUntil is the end of the flood wait which was longer than the retries could wait.
+	pausedUntil time.Time
+}
+
+// NewTelegramNotifier creates a new Telegram notifier which posts the buffered messages until the context is done.
+func NewTelegramNotifier(ctx context.Context, conf *TelegramConfig) (*TelegramNotifier, error) {
+	if len(conf.BotToken) == 0 || len(conf.ChatID) == 0 {
+		return nil, errors.New("telegram bot token and chat id are required")
+	}
+	if len(conf.APIURL) == 0 {
+		conf.APIURL = DefaultTelegramAPIURL
+	}
+	if conf.NotifyIntervalSeconds == 0 {
+		conf.NotifyIntervalSeconds = 15
+	}
+	if conf.MaxBufferSize == 0 {
+		conf.MaxBufferSize = 500
+	}
+	tn := &TelegramNotifier{config: conf, retryer: newRetryer(conf.Client, conf.Retry)}
+	tn.retryer.retryAfter = telegramRetryAfter
+	go tn.loop(ctx)
+	return tn, nil
+}
+
+// Notify buffers the finding to send in the next batch.
+func (tn *TelegramNotifier) Notify(ctx context.Context, finding *agent.Finding) error {
+	tn.mu.Lock()
+	defer tn.mu.Unlock()
+	tn.buf = append(tn.buf, renderFinding(tn.config.Templates, DestinationTelegram, finding, formatTelegramMessage))
+	tn.trimBuffer()
+	return nil
+}
+
+// trimBuffer drops the oldest messages which do not fit into the buffer.
+func (tn *TelegramNotifier) trimBuffer() {
+	overflow := len(tn.buf) - tn.config.MaxBufferSize
+	if overflow <= 0 {
+		return
+	}
+	log.Printf("telegram buffer is full: dropping the oldest %d messages", overflow)
+	tn.buf = tn.buf[overflow:]
+}
+
+func formatTelegramMessage(finding *agent.Finding) string {
+	lines := []string{
+		fmt.Sprintf("*%s*", format.EscapeMarkdownV2(findingTitle(finding))),
+		fmt.Sprintf("*Tx:* %s", telegramLink(format.FindingRef(finding), format.FindingURL(finding))),
+	}
+	for _, f := range findingFields(finding) {
+		value := format.EscapeMarkdownV2(truncate(f.Value, telegramValueLimit))
+		if common.IsHexAddress(f.Value) {
+			value = telegramLink(f.Value, format.AddressURL(f.Value))
+		}
+		if len(f.Label) > 0 {
+			value += format.EscapeMarkdownV2(fmt.Sprintf(" (%s)", f.Label))
+		}
+		lines = append(lines, fmt.Sprintf("*%s:* %s", format.EscapeMarkdownV2(f.Title), value))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func telegramLink(text, url string) string {
+	return fmt.Sprintf("[%s](%s)", format.EscapeMarkdownV2(text), telegramURLEscaper.Replace(url))
+}
+
+func (tn *TelegramNotifier) loop(ctx context.Context) {
+	ticker := time.NewTicker(time.Second * time.Duration(tn.config.NotifyIntervalSeconds))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			tn.postBufferedMessages(ctx)
+		}
+	}
+}
+
+// postBufferedMessages posts the buffered messages unless a flood wait is in progress. It stops at
+// the first temporary failure and keeps the unsent messages for the next tick. The messages which
+// fail permanently are dropped so that they do not block the rest.
+func (tn *TelegramNotifier) postBufferedMessages(ctx context.Context) {
+	tn.mu.Lock()
+	if time.Now().Before(tn.pausedUntil) {
+		tn.mu.Unlock()
+		return
+	}
+	messages := tn.buf
+	tn.buf = nil
+	tn.mu.Unlock()
+
+	texts := joinMessages(messages, "\n\n", telegramMessageLimit)
+	for i, text := range texts {
+		err := tn.sendMessage(ctx, text)
+		if err == nil {
+			continue
+		}
+		se := lastStatusError(err)
+		if se != nil && !se.temporary() {
+			log.Printf("dropping the telegram message after a permanent error: %v", err)
+			continue
+		}
+		log.Printf("failed to post the telegram message: %v", err)
+		tn.mu.Lock()
+		if se != nil && se.RetryAfter > 0 {
+			tn.pausedUntil = time.Now().Add(se.RetryAfter)
+		}
+		tn.buf = append(texts[i:], tn.buf...)
+		tn.trimBuffer()
+		tn.mu.Unlock()
+		return
+	}
+}
+
+func (tn *TelegramNotifier) sendMessage(ctx context.Context, text string) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"chat_id":                  tn.config.ChatID,
+		"text":                     text,
+		"parse_mode":               "MarkdownV2",
+		"disable_web_page_preview": true,
+	})
+	if err != nil {
+		return err
+	}
+	url := fmt.Sprintf("%s/bot%s/sendMessage", tn.config.APIURL, tn.config.BotToken)
+	return tn.retryer.do(ctx, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
+	})
+}
+
+// telegramRetryAfter reads the flood wait time from the error response.
+func telegramRetryAfter(resp *http.Response, body []byte) time.Duration {
+	var floodWait struct {
+		Parameters struct {
+			RetryAfter int `json:"retry_after"`
+		} `json:"parameters"`
+	}
+	if err := json.Unmarshal(body, &floodWait); err != nil || floodWait.Parameters.RetryAfter <= 0 {
+		return retryAfterHeader(resp, body)
+	}
+	return time.Duration(floodWait.Parameters.RetryAfter) * time.Second
+}
+
+// joinMessages joins the messages into as few texts as possible without exceeding the limit.
+// The messages longer than the limit are split first.
+func joinMessages(messages []string, sep string, limit int) []string {
+	var (
+		texts   []string
+		current string
+	)
+	var parts []string
+	for _, msg := range messages {
+		parts = append(parts, splitMessage(msg, limit)...)
+	}
+	for _, msg := range parts {
+		if len(current) == 0 {
+			current = msg
+			continue
+		}
+		if len(current)+len(sep)+len(msg) > limit {
+			texts = append(texts, current)
+			current = msg
+			continue
+		}
+		current += sep + msg
+	}
+	if len(current) > 0 {
+		texts = append(texts, current)
+	}
+	return texts
+}
+
+// splitMessage splits the message into the parts within the limit, at the line breaks if possible.
+// A part does not end in the middle of a character or a MarkdownV2 escape.
+func splitMessage(msg string, limit int) []string {
+	var parts []string
+	for len(msg) > limit {
+		cut := strings.LastIndex(msg[:limit], "\n")
+		if cut <= 0 {
+			cut = limit
+			for cut > 0 && !utf8.RuneStart(msg[cut]) {
+				cut--
+			}
+			backslashes := 0
+			for i := cut - 1; i >= 0 && msg[i] == '\\'; i-- {
+				backslashes++
+			}
+			if backslashes%2 == 1 {
+				cut--
+			}
+			if cut <= 0 {
+				cut = limit
+			}
+		}
+		parts = append(parts, msg[:cut])
+		msg = strings.TrimPrefix(msg[cut:], "\n")
+	}
+	if len(msg) > 0 {
+		parts = append(parts, msg)
+	}
+	return parts
+}
@@ -0,0 +1,176 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTelegramRetryAfter(t *testing.T) {
+	resp := &http.Response{Header: http.Header{"Retry-After": []string{"3"}}}
+	body := []byte(`{"ok":false,"error_code":429,"description":"Too Many Requests: retry after 7","parameters":{"retry_after":7}}`)
+	if wait := telegramRetryAfter(resp, body); wait != time.Second*7 {
+		t.Fatalf("expected the wait in the body, got %s", wait)
+	}
+	if wait := telegramRetryAfter(resp, []byte(`{"ok":false}`)); wait != time.Second*3 {
+		t.Fatalf("expected the wait in the header, got %s", wait)
+	}
+}
+
+func TestTelegramWaitsForFloodWait(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/bottoken/sendMessage" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		var msg struct {
+			ChatID string `json:"chat_id"`
+		}
+		json.NewDecoder(r.Body).Decode(&msg)
+		if msg.ChatID != "chat" {
+			t.Errorf("unexpected chat id %s", msg.ChatID)
+		}
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"ok":false,"error_code":429,"parameters":{"retry_after":1}}`))
+			return
+		}
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tn, err := NewTelegramNotifier(ctx, &TelegramConfig{
+		BotToken:              "token",
+		ChatID:                "chat",
+		NotifyIntervalSeconds: 3600,
+		APIURL:                srv.URL,
+		Retry:                 RetryConfig{InitialBackoffMs: 1, MaxBackoffMs: 2000},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	if err := tn.sendMessage(ctx, "hello"); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Fatalf("expected to wait for the flood wait, took %s", elapsed)
+	}
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Fatalf("expected 2 attempts, got %d", n)
+	}
+}
+
+func newTestTelegramNotifier(t *testing.T, apiURL string) *TelegramNotifier {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	tn, err := NewTelegramNotifier(ctx, &TelegramConfig{
+		BotToken:              "token",
+		ChatID:                "chat",
+		NotifyIntervalSeconds: 3600,
+		APIURL:                apiURL,
+		Retry:                 RetryConfig{InitialBackoffMs: 1, MaxBackoffMs: 5},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tn
+}
+
+func TestTelegramPausesForLongFloodWait(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"ok":false,"error_code":429,"parameters":{"retry_after":3600}}`))
+	}))
+	defer srv.Close()
+
+	tn := newTestTelegramNotifier(t, srv.URL)
+	tn.buf = []string{"first", "second"}
+	tn.postBufferedMessages(context.Background())
+	tn.postBufferedMessages(context.Background())
+
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("expected no requests during the flood wait, got %d", n)
+	}
+	if until := time.Until(tn.pausedUntil); until < time.Minute*59 {
+		t.Fatalf("expected to pause for the flood wait, got %s", until)
+	}
+	if len(tn.buf) != 1 || tn.buf[0] != "first\n\nsecond" {
+		t.Fatalf("expected the messages to be kept, got %q", tn.buf)
+	}
+}
+
+func TestTelegramDropsPermanentFailures(t *testing.T) {
+	var texts []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg struct {
+			Text string `json:"text"`
+		}
+		json.NewDecoder(r.Body).Decode(&msg)
+		texts = append(texts, msg.Text)
+		if len(texts) == 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"ok":false,"error_code":400,"description":"Bad Request: can't parse entities"}`))
+			return
+		}
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer srv.Close()
+
+	tn := newTestTelegramNotifier(t, srv.URL)
+	tn.buf = []string{strings.Repeat("a", telegramMessageLimit), "next"}
+	tn.postBufferedMessages(context.Background())
+
+	if len(texts) != 2 || texts[1] != "next" {
+		t.Fatalf("expected the next message to be sent after the failed one, got %d requests", len(texts))
+	}
+	if len(tn.buf) != 0 {
+		t.Fatalf("expected the failed message to be dropped, got %d kept", len(tn.buf))
+	}
+}
+
+func TestSplitMessage(t *testing.T) {
+	lines := strings.TrimSuffix(strings.Repeat("line\n", 10), "\n")
+	parts := splitMessage(lines, 12)
+	for _, part := range parts {
+		if len(part) > 12 || strings.HasPrefix(part, "\n") {
+			t.Fatalf("unexpected part %q", part)
+		}
+	}
+	if strings.Join(parts, "\n") != lines {
+		t.Fatalf("expected the lines to be kept, got %q", parts)
+	}
+
+	// A long line is not cut in the middle of a character or an escape.
+	parts = splitMessage("ab\\.cd", 3)
+	if parts[0] != "ab" {
+		t.Fatalf("expected the escape to be kept together, got %q", parts)
+	}
+	parts = splitMessage("aé", 2)
+	if parts[0] != "a" || parts[1] != "é" {
+		t.Fatalf("expected the character to be kept together, got %q", parts)
+	}
+}
+
+func TestJoinMessagesSplitsLongMessages(t *testing.T) {
+	long := strings.Repeat("x", telegramMessageLimit+10)
+	texts := joinMessages([]string{"short", long}, "\n\n", telegramMessageLimit)
+	if len(texts) != 3 {
+		t.Fatalf("expected 3 texts, got %d", len(texts))
+	}
+	for _, text := range texts {
+		if len(text) > telegramMessageLimit {
+			t.Fatalf("text is longer than the limit: %d", len(text))
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/canercidam/large-tx-detector/core/agent"
+)
+
+// Config vars
+var (
+	DefaultSignatureHeader = "X-Signature-256"
+)
+
+// WebhookConfig contains the webhook notifier config parameters.
+type WebhookConfig struct {
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+	// Secret is used to sign the request body with HMAC-SHA256, if specified.
+	Secret          string      `json:"secret"`
+	SignatureHeader string      `json:"signatureHeader"`
+	Retry           RetryConfig `json:"retry"`
+
+	// Client is used for the requests if specified.
+	Client *http.Client `json:"-"`
+}
+
+// WebhookNotifier posts the findings as JSON to a URL.
+type WebhookNotifier struct {
+	config  *WebhookConfig
+	retryer *retryer
+}
+
+// NewWebhookNotifier creates a new webhook notifier.
+func NewWebhookNotifier(conf *WebhookConfig) (*WebhookNotifier, error) {
+	if len(conf.URL) == 0 {
+		return nil, errors.New("webhook url is required")
+	}
+	if len(conf.SignatureHeader) == 0 {
+		conf.SignatureHeader = DefaultSignatureHeader
+	}
+	return &WebhookNotifier{config: conf, retryer: newRetryer(conf.Client, conf.Retry)}, nil
+}
+
+// Notify posts the finding to the webhook.
+func (wn *WebhookNotifier) Notify(ctx context.Context, finding *agent.Finding) error {
+	body, err := json.Marshal(finding)
+	if err != nil {
+		return fmt.Errorf("failed to encode the finding: %v", err)
+	}
+	return wn.retryer.do(ctx, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, wn.config.URL, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		for k, v := range wn.config.Headers {
+			req.Header.Set(k, v)
+		}
+		if len(wn.config.Secret) > 0 {
+			req.Header.Set(wn.config.SignatureHeader, sign(wn.config.Secret, body))
+		}
+		return req, nil
+	})
+}
+
+// sign makes the signature header value of the body.
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,73 @@
+package notifier
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/canercidam/large-tx-detector/core/agent"
+)
+
+func TestWebhookNotifierSignsEveryAttempt(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		bodies []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mac := hmac.New(sha256.New, []byte("secret"))
+		mac.Write(body)
+		if expected := "sha256=" + hex.EncodeToString(mac.Sum(nil)); r.Header.Get("X-Signature-256") != expected {
+			t.Errorf("unexpected signature %s", r.Header.Get("X-Signature-256"))
+		}
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" || r.Header.Get("X-Custom") != "value" {
+			t.Errorf("unexpected request %s %v", r.Method, r.Header)
+		}
+
+		mu.Lock()
+		bodies = append(bodies, string(body))
+		attempt := len(bodies)
+		mu.Unlock()
+		if attempt == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+
+	wn, err := NewWebhookNotifier(&WebhookConfig{
+		URL:     srv.URL,
+		Headers: map[string]string{"X-Custom": "value"},
+		Secret:  "secret",
+		Retry:   RetryConfig{InitialBackoffMs: 1, MaxBackoffMs: 5},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	finding := &agent.Finding{AgentID: "default-agent", TxHash: "0xabc", Metadata: map[string]string{agent.MetadataAmount: "1000"}}
+	if err := wn.Notify(context.Background(), finding); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(bodies) != 2 {
+		t.Fatalf("expected 2 attempts, got %d", len(bodies))
+	}
+	if bodies[0] != bodies[1] || len(bodies[1]) == 0 {
+		t.Fatalf("expected the same body on the retry, got '%s' and '%s'", bodies[0], bodies[1])
+	}
+	var received agent.Finding
+	if err := json.Unmarshal([]byte(bodies[1]), &received); err != nil {
+		t.Fatal(err)
+	}
+	if received.TxHash != "0xabc" || received.Metadata[agent.MetadataAmount] != "1000" {
+		t.Fatalf("unexpected finding %+v", received)
+	}
+}
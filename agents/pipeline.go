@@ -0,0 +1,123 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+
+	"github.com/canercidam/large-tx-detector/core/agent"
+	"github.com/canercidam/large-tx-detector/format"
+	"github.com/canercidam/large-tx-detector/labels"
+	"github.com/canercidam/large-tx-detector/rules"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// findingPipeline takes the common steps of all agents before notifying about a finding: it
+// attaches the labels, and checks the ignore rules, the filter rules and the actions taken on
+// the previous alerts. All steps are optional.
+type findingPipeline struct {
+	agentID  string
+	labels   *labels.Registry
+	ignore   *IgnoreList
+	rules    *rules.Engine
+	actions  agent.ActionRepository
+	notifier agent.Notifier
+}
+
+// notify notifies about the finding unless it is suppressed. The tx is nil for the block findings.
+func (fp *findingPipeline) notify(ctx context.Context, finding *agent.Finding, tx *types.Transaction) error {
+	if fp.labels != nil {
+		if err := fp.labels.Annotate(finding); err != nil {
+			return err
+		}
+	}
+	if rule, ok := fp.ignore.Match(finding); ok {
+		log.Printf("suppressed %s finding for %s by ignore rule '%s'", finding.AlertType, format.FindingRef(finding), rule.Name)
+		suppressedFindings.Add(fmt.Sprintf("%s/%s", fp.agentID, rule.Name), 1)
+		return nil
+	}
+	rule, err := fp.rules.Filter(ctx, finding, tx)
+	if err != nil {
+		return err
+	}
+	if rule != nil {
+		log.Printf("filtered %s finding for %s by rule '%s'", finding.AlertType, format.FindingRef(finding), rule.Name)
+		return nil
+	}
+	suppressed, err := fp.isSuppressed(finding)
+	if err != nil {
+		return err
+	}
+	if suppressed {
+		return nil
+	}
+	return fp.notifier.Notify(ctx, finding)
+}
+
+// isSuppressed checks the actions taken on the previous alerts.
+func (fp *findingPipeline) isSuppressed(finding *agent.Finding) (bool, error) {
+	if fp.actions == nil {
+		return false, nil
+	}
+
+	if subject := agent.AckSubject(finding); len(subject) > 0 {
+		ack, err := fp.actions.GetAck(fp.agentID, subject)
+		if err != nil {
+			return false, fmt.Errorf("failed to get the ack: %v", err)
+		}
+		if ack != nil {
+			return true, nil
+		}
+	}
+
+	for _, key := range []string{agent.MetadataFrom, agent.MetadataTo} {
+		address := finding.Metadata[key]
+		if len(address) == 0 {
+			continue
+		}
+		mute, err := fp.actions.GetMute(address)
+		if err != nil {
+			return false, fmt.Errorf("failed to get the mute: %v", err)
+		}
+		if mute != nil && time.Now().Before(mute.Until) {
+			return true, nil
+		}
+	}
+
+	minAmount, err := fp.actions.GetMinAmount(fp.agentID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get the min amount: %v", err)
+	}
+	if minAmount == nil {
+		return false, nil
+	}
+	min, ok := big.NewRat(0, 1).SetString(minAmount.Amount)
+	if !ok {
+		return false, nil
+	}
+	amount, ok := big.NewRat(0, 1).SetString(finding.Metadata[agent.MetadataAmount])
+	if !ok {
+		return false, nil
+	}
+	return amount.Cmp(min) < 0, nil
+}
+
+// newFindingPipeline creates the pipeline of an agent. The default log notifier is used if the notifier is not specified.
+func newFindingPipeline(
+	agentID string, notifier agent.Notifier, templates *format.Templates,
+	labels *labels.Registry, ignore *IgnoreList, rules *rules.Engine, actions agent.ActionRepository,
+) *findingPipeline {
+	if notifier == nil {
+		notifier = &defaultLTNotifier{templates: templates}
+	}
+	return &findingPipeline{
+		agentID:  agentID,
+		labels:   labels,
+		ignore:   ignore,
+		rules:    rules,
+		actions:  actions,
+		notifier: notifier,
+	}
+}
@@ -0,0 +1,131 @@
+package agents
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/canercidam/large-tx-detector/core/agent"
+)
+
+// memActions keeps the actions in memory.
+type memActions struct {
+	mutes      map[string]*agent.Mute
+	acks       map[string]*agent.Ack
+	minAmounts map[string]*agent.MinAmount
+}
+
+func newMemActions() *memActions {
+	return &memActions{
+		mutes:      make(map[string]*agent.Mute),
+		acks:       make(map[string]*agent.Ack),
+		minAmounts: make(map[string]*agent.MinAmount),
+	}
+}
+
+func (m *memActions) SaveMute(mute *agent.Mute) error {
+	m.mutes[mute.Address] = mute
+	return nil
+}
+
+func (m *memActions) GetMute(address string) (*agent.Mute, error) {
+	return m.mutes[address], nil
+}
+
+func (m *memActions) SaveAck(ack *agent.Ack) error {
+	m.acks[ack.AgentID+"/"+ack.Subject] = ack
+	return nil
+}
+
+func (m *memActions) GetAck(agentID, subject string) (*agent.Ack, error) {
+	return m.acks[agentID+"/"+subject], nil
+}
+
+func (m *memActions) SaveMinAmount(minAmount *agent.MinAmount) error {
+	m.minAmounts[minAmount.AgentID] = minAmount
+	return nil
+}
+
+func (m *memActions) GetMinAmount(agentID string) (*agent.MinAmount, error) {
+	return m.minAmounts[agentID], nil
+}
+
+// countingNotifier counts the notified findings.
+type countingNotifier struct {
+	count int
+}
+
+func (n *countingNotifier) Notify(ctx context.Context, finding *agent.Finding) error {
+	n.count++
+	return nil
+}
+
+func testFinding(txHash, from, to string) *agent.Finding {
+	return &agent.Finding{
+		AgentID:  "default-agent",
+		TxHash:   txHash,
+		Metadata: map[string]string{agent.MetadataFrom: from, agent.MetadataTo: to},
+	}
+}
+
+func TestPipelineAckSuppressesTheSameSubject(t *testing.T) {
+	actions := newMemActions()
+	notifier := &countingNotifier{}
+	fp := newFindingPipeline("default-agent", notifier, nil, nil, nil, nil, actions)
+
+	acked := testFinding("0x1", "0xA", "0xB")
+	if err := actions.SaveAck(&agent.Ack{AgentID: "default-agent", Subject: agent.AckSubject(acked), TxHash: "0x1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if err := fp.notify(ctx, testFinding("0x2", "0xa", "0xb"), nil); err != nil {
+		t.Fatal(err)
+	}
+	if notifier.count != 0 {
+		t.Fatal("expected the finding with the acked subject to be suppressed")
+	}
+	if err := fp.notify(ctx, testFinding("0x3", "0xa", "0xc"), nil); err != nil {
+		t.Fatal(err)
+	}
+	if notifier.count != 1 {
+		t.Fatal("expected the finding with another subject to be notified")
+	}
+}
+
+func TestPipelineMuteSuppressesTheAddress(t *testing.T) {
+	actions := newMemActions()
+	notifier := &countingNotifier{}
+	fp := newFindingPipeline("default-agent", notifier, nil, nil, nil, nil, actions)
+	actions.SaveMute(&agent.Mute{Address: "0xB", Until: time.Now().Add(time.Hour)})
+	actions.SaveMute(&agent.Mute{Address: "0xC", Until: time.Now().Add(-time.Hour)})
+
+	ctx := context.Background()
+	for _, finding := range []*agent.Finding{testFinding("0x1", "0xA", "0xB"), testFinding("0x2", "0xC", "0xD")} {
+		if err := fp.notify(ctx, finding, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if notifier.count != 1 {
+		t.Fatalf("expected only the finding without an active mute to be notified, got %d", notifier.count)
+	}
+}
+
+func TestPipelineMinAmountSuppressesSmallerAmounts(t *testing.T) {
+	actions := newMemActions()
+	notifier := &countingNotifier{}
+	fp := newFindingPipeline("default-agent", notifier, nil, nil, nil, nil, actions)
+	actions.SaveMinAmount(&agent.MinAmount{AgentID: "default-agent", Amount: "1000.5"})
+
+	ctx := context.Background()
+	for _, amount := range []string{"999.99", "1000.5", "2000"} {
+		finding := testFinding("0x1", "0xA", "0xB")
+		finding.Metadata[agent.MetadataAmount] = amount
+		if err := fp.notify(ctx, finding, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if notifier.count != 2 {
+		t.Fatalf("expected the amounts from the min amount to be notified, got %d", notifier.count)
+	}
+}
@@ -0,0 +1,57 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/canercidam/large-tx-detector/contracts"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Errors
+var (
+	ErrStalePrice = errors.New("price is stale")
+)
+
+// PriceFeed reads the USD price of a token from a Chainlink aggregator.
+type PriceFeed struct {
+	aggregator *contracts.AggregatorV3
+	maxAge     time.Duration
+	exp        *big.Int
+}
+
+// NewPriceFeed creates a new price feed. The answers older than the max age are rejected.
+func NewPriceFeed(caller bind.ContractCaller, address string, maxAge time.Duration) (*PriceFeed, error) {
+	aggregator, err := contracts.NewAggregatorV3(common.HexToAddress(address), caller)
+	if err != nil {
+		return nil, err
+	}
+	return &PriceFeed{aggregator: aggregator, maxAge: maxAge}, nil
+}
+
+// LatestPrice returns the latest price after checking that it is fresh.
+func (pf *PriceFeed) LatestPrice(ctx context.Context) (*big.Rat, error) {
+	if pf.exp == nil {
+		decimals, err := pf.aggregator.Decimals(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the price feed decimals: %v", err)
+		}
+		pf.exp = big.NewInt(0).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+	}
+
+	round, err := pf.aggregator.LatestRoundData(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the latest price: %v", err)
+	}
+	if round.Answer.Sign() <= 0 {
+		return nil, fmt.Errorf("invalid price %s", round.Answer)
+	}
+	if round.AnsweredInRound.Cmp(round.RoundID) < 0 || time.Since(round.UpdatedAt) > pf.maxAge {
+		return nil, ErrStalePrice
+	}
+	return big.NewRat(0, 1).SetFrac(round.Answer, pf.exp), nil
+}
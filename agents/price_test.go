@@ -0,0 +1,162 @@
+package agents
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/canercidam/large-tx-detector/contracts"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+const testPriceFeedAddress = "0x5f4eC3Df9cbd43714FE2740f5E3616155c5b8419"
+
+// fakePriceFeed serves the price feed calls over JSON-RPC.
+type fakePriceFeed struct {
+	abi             abi.ABI
+	decimals        uint8
+	roundID         int64
+	answer          int64
+	updatedAt       time.Time
+	answeredInRound int64
+	decimalsCalls   int
+}
+
+func (f *fakePriceFeed) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID     json.RawMessage   `json:"id"`
+		Method string            `json:"method"`
+		Params []json.RawMessage `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Method != "eth_call" || len(req.Params) == 0 {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	var call struct {
+		Data  string `json:"data"`
+		Input string `json:"input"`
+	}
+	json.Unmarshal(req.Params[0], &call)
+	data := call.Data
+	if len(data) == 0 {
+		data = call.Input
+	}
+	selector, _ := hex.DecodeString(strings.TrimPrefix(data, "0x"))
+
+	var (
+		out []byte
+		err error
+	)
+	switch {
+	case len(selector) >= 4 && string(selector[:4]) == string(f.abi.Methods["decimals"].ID):
+		f.decimalsCalls++
+		out, err = f.abi.Methods["decimals"].Outputs.Pack(f.decimals)
+	case len(selector) >= 4 && string(selector[:4]) == string(f.abi.Methods["latestRoundData"].ID):
+		out, err = f.abi.Methods["latestRoundData"].Outputs.Pack(
+			big.NewInt(f.roundID), big.NewInt(f.answer), big.NewInt(f.updatedAt.Unix()),
+			big.NewInt(f.updatedAt.Unix()), big.NewInt(f.answeredInRound),
+		)
+	default:
+		http.Error(w, "unknown method", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      req.ID,
+		"result":  "0x" + hex.EncodeToString(out),
+	})
+}
+
+func newTestPriceFeed(t *testing.T, fake *fakePriceFeed) *PriceFeed {
+	parsed, err := abi.JSON(strings.NewReader(contracts.AggregatorV3ABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake.abi = parsed
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	client, err := ethclient.Dial(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(client.Close)
+	pf, err := NewPriceFeed(client, testPriceFeedAddress, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pf
+}
+
+func TestPriceFeedLatestPrice(t *testing.T) {
+	fake := &fakePriceFeed{
+		decimals:        8,
+		roundID:         10,
+		answer:          200012345678,
+		updatedAt:       time.Now().Add(-time.Minute),
+		answeredInRound: 10,
+	}
+	pf := newTestPriceFeed(t, fake)
+
+	for i := 0; i < 2; i++ {
+		price, err := pf.LatestPrice(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if price.FloatString(8) != "2000.12345678" {
+			t.Fatalf("unexpected price %s", price.FloatString(8))
+		}
+	}
+	if fake.decimalsCalls != 1 {
+		t.Fatalf("expected the decimals to be read once, got %d", fake.decimalsCalls)
+	}
+}
+
+func TestPriceFeedRejectsStalePrices(t *testing.T) {
+	tests := []struct {
+		name            string
+		updatedAt       time.Time
+		answeredInRound int64
+	}{
+		{name: "old update", updatedAt: time.Now().Add(-time.Hour * 2), answeredInRound: 10},
+		{name: "old round", updatedAt: time.Now(), answeredInRound: 9},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pf := newTestPriceFeed(t, &fakePriceFeed{
+				decimals:        8,
+				roundID:         10,
+				answer:          100000000,
+				updatedAt:       test.updatedAt,
+				answeredInRound: test.answeredInRound,
+			})
+			if _, err := pf.LatestPrice(context.Background()); err != ErrStalePrice {
+				t.Fatalf("expected a stale price error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestPriceFeedRejectsInvalidPrices(t *testing.T) {
+	pf := newTestPriceFeed(t, &fakePriceFeed{
+		decimals:        8,
+		roundID:         10,
+		answer:          0,
+		updatedAt:       time.Now(),
+		answeredInRound: 10,
+	})
+	if _, err := pf.LatestPrice(context.Background()); err == nil || err == ErrStalePrice {
+		t.Fatalf("expected an invalid price error, got %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+package agents
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/canercidam/large-tx-detector/core/agent"
+)
+
+// SeverityTier maps the transfers above a threshold to a severity. The threshold is
+// in token units, or in USD if USD is set.
+type SeverityTier struct {
+	Severity  agent.Severity
+	Threshold uint64
+	USD       bool
+}
+
+// ParseSeverityTiers parses the tiers like "info:1000000" or "critical:$100000000" for the USD thresholds.
+func ParseSeverityTiers(specs []string) ([]*SeverityTier, error) {
+	var tiers []*SeverityTier
+	for _, spec := range specs {
+		parts := strings.SplitN(strings.TrimSpace(spec), ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid severity tier '%s'", spec)
+		}
+		tier := &SeverityTier{Severity: agent.Severity(strings.ToLower(parts[0]))}
+		if tier.Severity.Rank() == 0 {
+			return nil, fmt.Errorf("unknown severity '%s'", parts[0])
+		}
+		threshold := parts[1]
+		if strings.HasPrefix(threshold, "$") {
+			tier.USD = true
+			threshold = threshold[1:]
+		}
+		var err error
+		tier.Threshold, err = strconv.ParseUint(threshold, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid severity tier threshold '%s': %v", parts[1], err)
+		}
+		tiers = append(tiers, tier)
+	}
+	return tiers, nil
+}
+
+// HasUSDTiers tells if any of the tiers has a USD threshold, which needs a price feed.
+func HasUSDTiers(tiers []*SeverityTier) bool {
+	for _, tier := range tiers {
+		if tier.USD {
+			return true
+		}
+	}
+	return false
+}
+
+// severityTiers are the tiers with the thresholds in the token base units and in USD.
+type severityTiers []*severityTier
+
+type severityTier struct {
+	severity     agent.Severity
+	threshold    *big.Int
+	usdThreshold *big.Rat
+}
+
+// newSeverityTiers converts the thresholds and sorts the tiers by descending severity.
+func newSeverityTiers(tiers []*SeverityTier, exp *big.Int) severityTiers {
+	var st severityTiers
+	for _, tier := range tiers {
+		threshold := big.NewInt(0).SetUint64(tier.Threshold)
+		if tier.USD {
+			st = append(st, &severityTier{severity: tier.Severity, usdThreshold: big.NewRat(0, 1).SetInt(threshold)})
+			continue
+		}
+		st = append(st, &severityTier{severity: tier.Severity, threshold: threshold.Mul(threshold, exp)})
+	}
+	sort.SliceStable(st, func(i, j int) bool {
+		return st[i].severity.Rank() > st[j].severity.Rank()
+	})
+	return st
+}
+
+// match returns the highest severity which the transfer reaches. The USD value is nil if it is not known.
+func (st severityTiers) match(value *big.Int, usdValue *big.Rat) (agent.Severity, bool) {
+	for _, tier := range st {
+		if tier.threshold != nil && value.Cmp(tier.threshold) >= 0 {
+			return tier.severity, true
+		}
+		if tier.usdThreshold != nil && usdValue != nil && usdValue.Cmp(tier.usdThreshold) >= 0 {
+			return tier.severity, true
+		}
+	}
+	return "", false
+}
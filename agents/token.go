@@ -0,0 +1,87 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/canercidam/large-tx-detector/clients"
+	"github.com/canercidam/large-tx-detector/contracts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// resolveTokenMetadata returns the token metadata with the symbol and the decimals from the config.
+// The metadata is read from the cache or the contract if any of them is not specified.
+func resolveTokenMetadata(
+	ctx context.Context, client *clients.RPC, tokens TokenRepository, address common.Address, symbol string, decimals int,
+) (*contracts.TokenMetadata, error) {
+	if len(symbol) > 0 && decimals > 0 {
+		return &contracts.TokenMetadata{Address: address.Hex(), Symbol: symbol, Decimals: decimals}, nil
+	}
+	token, err := getTokenMetadata(ctx, client, tokens, address)
+	if err != nil {
+		return nil, err
+	}
+	// Config values override the token metadata.
+	if len(symbol) > 0 {
+		token.Symbol = symbol
+	}
+	if decimals > 0 {
+		token.Decimals = decimals
+	}
+	return token, nil
+}
+
+func getTokenMetadata(ctx context.Context, client *clients.RPC, tokens TokenRepository, address common.Address) (*contracts.TokenMetadata, error) {
+	if tokens != nil {
+		token, err := tokens.GetTokenMetadata(address.Hex())
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the cached token metadata: %v", err)
+		}
+		if token != nil {
+			return token, nil
+		}
+	}
+
+	token, err := contracts.ReadTokenMetadata(ctx, client, address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the token metadata: %v", err)
+	}
+	log.Printf("token %s: name=%s symbol=%s decimals=%d", token.Address, token.Name, token.Symbol, token.Decimals)
+	if tokens != nil {
+		if err := tokens.SaveTokenMetadata(token); err != nil {
+			return nil, fmt.Errorf("failed to cache the token metadata: %v", err)
+		}
+	}
+	return token, nil
+}
+
+// getBlockReceipts gets the receipts of all transactions in the block.
+func getBlockReceipts(ctx context.Context, client *clients.RPC, block *types.Block) ([]*types.Receipt, error) {
+	var txHashes []common.Hash
+	for _, tx := range block.Transactions() {
+		txHashes = append(txHashes, tx.Hash())
+	}
+	receipts, err := client.BatchGetTransactionReceipt(ctx, txHashes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the transaction logs for block %d: %v", block.NumberU64(), err)
+	}
+	return receipts, nil
+}
+
+// findLogs returns the logs of the tx which the contract emitted with the event topic.
+func findLogs(receipts []*types.Receipt, tx *types.Transaction, contract common.Address, topic common.Hash) []*types.Log {
+	var logs []*types.Log
+	for _, receipt := range receipts {
+		if receipt.TxHash != tx.Hash() {
+			continue
+		}
+		for _, txLog := range receipt.Logs {
+			if txLog.Address == contract && len(txLog.Topics) > 0 && txLog.Topics[0] == topic {
+				logs = append(logs, txLog)
+			}
+		}
+	}
+	return logs
+}
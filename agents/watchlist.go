@@ -0,0 +1,154 @@
+package agents
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Watch directions
+const (
+	DirectionIn   = "in"
+	DirectionOut  = "out"
+	DirectionBoth = "both"
+)
+
+// WatchlistEntry alerts about the transfers of an address above its own threshold.
+type WatchlistEntry struct {
+	Watchlist string `json:"watchlist"`
+	Address   string `json:"address"`
+	// Threshold is in token units. Zero alerts about any movement.
+	Threshold string `json:"threshold"`
+	Direction string `json:"direction"`
+	// Token limits the entry to a token address or symbol. Empty matches all tokens.
+	Token string `json:"token,omitempty"`
+}
+
+// Validate checks and normalizes the entry.
+func (entry *WatchlistEntry) Validate() error {
+	if len(entry.Watchlist) == 0 {
+		return errors.New("watchlist name is required")
+	}
+	if !common.IsHexAddress(entry.Address) {
+		return fmt.Errorf("invalid address '%s'", entry.Address)
+	}
+	entry.Address = common.HexToAddress(entry.Address).Hex()
+	if len(entry.Threshold) == 0 {
+		entry.Threshold = "0"
+	}
+	if _, ok := big.NewRat(0, 1).SetString(entry.Threshold); !ok {
+		return fmt.Errorf("invalid threshold '%s'", entry.Threshold)
+	}
+	switch entry.Direction {
+	case "":
+		entry.Direction = DirectionBoth
+	case DirectionIn, DirectionOut, DirectionBoth:
+	default:
+		return fmt.Errorf("invalid direction '%s'", entry.Direction)
+	}
+	return nil
+}
+
+// WatchlistRepository stores the watchlist entries.
+type WatchlistRepository interface {
+	SaveWatchlistEntry(*WatchlistEntry) error
+	DeleteWatchlistEntry(watchlist, address string) error
+	GetWatchlistEntries(address string) ([]*WatchlistEntry, error)
+	ListWatchlistEntries() ([]*WatchlistEntry, error)
+}
+
+// LoadWatchlist reads the entries from a JSON file and saves them.
+func LoadWatchlist(repo WatchlistRepository, path string) (int, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	var entries []*WatchlistEntry
+	if err := json.Unmarshal(b, &entries); err != nil {
+		return 0, fmt.Errorf("failed to decode the watchlist: %v", err)
+	}
+	for i, entry := range entries {
+		if err := entry.Validate(); err != nil {
+			return i, fmt.Errorf("invalid entry %d: %v", i, err)
+		}
+		if err := repo.SaveWatchlistEntry(entry); err != nil {
+			return i, fmt.Errorf("failed to save entry %d: %v", i, err)
+		}
+	}
+	return len(entries), nil
+}
+
+// WatchlistHandler lists (GET), saves (PUT) and deletes (DELETE) the watchlist entries.
+type WatchlistHandler struct {
+	repo WatchlistRepository
+}
+
+// NewWatchlistHandler creates a new watchlist HTTP handler.
+func NewWatchlistHandler(repo WatchlistRepository) *WatchlistHandler {
+	return &WatchlistHandler{repo: repo}
+}
+
+// ServeHTTP implements http.Handler.
+func (wh *WatchlistHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		var (
+			entries []*WatchlistEntry
+			err     error
+		)
+		if address := r.URL.Query().Get("address"); len(address) > 0 {
+			entries, err = wh.repo.GetWatchlistEntries(address)
+		} else {
+			entries, err = wh.repo.ListWatchlistEntries()
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(entries)
+
+	case http.MethodPut, http.MethodPost:
+		var entry WatchlistEntry
+		if err := json.NewDecoder(r.Body).Decode(&entry); err != nil {
+			http.Error(w, fmt.Sprintf("invalid entry: %v", err), http.StatusBadRequest)
+			return
+		}
+		if err := entry.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := wh.repo.SaveWatchlistEntry(&entry); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&entry)
+
+	case http.MethodDelete:
+		watchlist, address := r.URL.Query().Get("watchlist"), r.URL.Query().Get("address")
+		if len(watchlist) == 0 || !common.IsHexAddress(address) {
+			http.Error(w, "watchlist and address are required", http.StatusBadRequest)
+			return
+		}
+		if err := wh.repo.DeleteWatchlistEntry(watchlist, address); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// matchesToken tells if the entry applies to the token.
+func (entry *WatchlistEntry) matchesToken(address, symbol string) bool {
+	return len(entry.Token) == 0 || strings.EqualFold(entry.Token, address) || strings.EqualFold(entry.Token, symbol)
+}
@@ -14,16 +14,70 @@ type envVars struct {
 	SlackOAuthToken            string `envconfig:"slack_oauth_token"`
 	SlackChannelID             string `envconfig:"slack_channel_id"`
 	SlackNotifyIntervalSeconds int    `envconfig:"slack_notify_interval_seconds" default:"15"`
+	SlackMaxRetries            int    `envconfig:"slack_max_retries" default:"3"`
+	SlackMaxBufferSize         int    `envconfig:"slack_max_buffer_size" default:"500"`
+	SlackThreadBy              string `envconfig:"slack_thread_by"`
+	SlackThreadWindowMinutes   int    `envconfig:"slack_thread_window_minutes" default:"60"`
+	SlackThreadBroadcast       bool   `envconfig:"slack_thread_broadcast"`
+	SlackSigningSecret         string `envconfig:"slack_signing_secret"`
+	HTTPAddress                string `envconfig:"http_address" default:":8080"`
 	EtherscanBaseURL           string `envconfig:"etherscan_base_url" default:"https://etherscan.io"`
+	NotificationRoutesPath     string `envconfig:"notification_routes_path"`
+	NotificationTemplatesPath  string `envconfig:"notification_templates_path"`
+
+	// LabelsPaths are the CSV or JSON files of the address labels.
+	LabelsPaths []string `envconfig:"labels_paths"`
+
+	// WatchlistPath is the JSON file of the watchlist entries which are saved at startup.
+	WatchlistPath string `envconfig:"watchlist_path"`
+	// IgnoreRulesPath is the JSON file of the rules which suppress the findings.
+	IgnoreRulesPath string `envconfig:"ignore_rules_path"`
+	// RulesPath is the JSON file of the filter expressions by agent ID.
+	RulesPath string `envconfig:"rules_path"`
+	// APIToken protects the HTTP API endpoints with bearer auth, if specified.
+	APIToken string `envconfig:"api_token"`
 
 	// Blockchain parameters
 	RequireBlockConfirmation uint64 `envconfig:"require_block_confirmation" default:"4"`
 
 	// Large tx detector config
-	WatchedTokenAddress   string `envconfig:"watched_token_address"`
-	WatchedTokenSymbol    string `envconfig:"watched_token_symbol"`
-	WatchedTokenDecimals  int    `envconfig:"watched_token_decimals"`
-	WatchedTokenThreshold uint64 `envconfig:"watched_token_threshold"`
+	WatchedTokenAddress       string `envconfig:"watched_token_address"`
+	WatchedTokenSymbol        string `envconfig:"watched_token_symbol"`
+	WatchedTokenDecimals      int    `envconfig:"watched_token_decimals"`
+	WatchedTokenThreshold     uint64 `envconfig:"watched_token_threshold"`
+	WatchedTokenMintThreshold uint64 `envconfig:"watched_token_mint_threshold"`
+	WatchedTokenBurnThreshold uint64 `envconfig:"watched_token_burn_threshold"`
+	// WatchedTokenSeverityTiers are like "info:1000000,high:10000000,critical:$100000000" ($ for USD).
+	WatchedTokenSeverityTiers []string `envconfig:"watched_token_severity_tiers"`
+
+	// Split transfer aggregation, enabled by setting the group to sender, receiver or pair
+	AggregateBy            string `envconfig:"aggregate_by"`
+	AggregateWindowBlocks  uint64 `envconfig:"aggregate_window_blocks"`
+	AggregateWindowSeconds uint64 `envconfig:"aggregate_window_seconds"`
+	AggregateThreshold     uint64 `envconfig:"aggregate_threshold"`
+
+	// Anomaly detection, enabled by setting the z-score threshold
+	AnomalyZScore         float64 `envconfig:"anomaly_z_score"`
+	AnomalyAlpha          float64 `envconfig:"anomaly_alpha" default:"0.01"`
+	AnomalyWarmupSamples  uint64  `envconfig:"anomaly_warmup_samples" default:"500"`
+	AnomalyBackfillBlocks uint64  `envconfig:"anomaly_backfill_blocks"`
+	AnomalyBackfillChunk  uint64  `envconfig:"anomaly_backfill_chunk_blocks" default:"2000"`
+
+	// Approval monitoring of the watched addresses and the watchlist
+	ApprovalMonitor          bool     `envconfig:"approval_monitor"`
+	ApprovalWatchedAddresses []string `envconfig:"approval_watched_addresses"`
+	ApprovalThreshold        uint64   `envconfig:"approval_threshold"`
+	ApprovalNewSpenderBlocks uint64   `envconfig:"approval_new_spender_blocks"`
+
+	// Supply monitoring, enabled by setting the interval
+	SupplyCheckIntervalBlocks uint64  `envconfig:"supply_check_interval_blocks"`
+	SupplyChangeThreshold     uint64  `envconfig:"supply_change_threshold"`
+	SupplyChangePercent       float64 `envconfig:"supply_change_percent"`
+
+	// USD valuation
+	WatchedTokenPriceFeed    string `envconfig:"watched_token_price_feed"`
+	WatchedTokenUSDThreshold uint64 `envconfig:"watched_token_usd_threshold"`
+	PriceFeedMaxAgeSeconds   int    `envconfig:"price_feed_max_age_seconds" default:"86400"`
 }
 
 // Vars are all available config variables in application environment.
@@ -23,3 +23,9 @@ func UnpackIERC20Transfer(contract *bind.BoundContract, log *types.Log) (*IERC20
 	var transferEvent IERC20Transfer
 	return &transferEvent, contract.UnpackLog(&transferEvent, "Transfer", *log)
 }
+
+// UnpackIERC20Approval unpacks the event.
+func UnpackIERC20Approval(contract *bind.BoundContract, log *types.Log) (*IERC20Approval, error) {
+	var approvalEvent IERC20Approval
+	return &approvalEvent, contract.UnpackLog(&approvalEvent, "Approval", *log)
+}
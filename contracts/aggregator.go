@@ -0,0 +1,83 @@
+package contracts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// AggregatorV3ABI is the ABI of the Chainlink price feed functions we need.
+const AggregatorV3ABI = `[
+	{"inputs":[],"name":"decimals","outputs":[{"name":"","type":"uint8"}],"stateMutability":"view","type":"function"},
+	{"inputs":[],"name":"latestRoundData","outputs":[
+		{"name":"roundId","type":"uint80"},
+		{"name":"answer","type":"int256"},
+		{"name":"startedAt","type":"uint256"},
+		{"name":"updatedAt","type":"uint256"},
+		{"name":"answeredInRound","type":"uint80"}
+	],"stateMutability":"view","type":"function"}
+]`
+
+// RoundData is the latest answer of a price feed.
+type RoundData struct {
+	RoundID         *big.Int
+	Answer          *big.Int
+	UpdatedAt       time.Time
+	AnsweredInRound *big.Int
+}
+
+// AggregatorV3 reads a Chainlink price feed.
+type AggregatorV3 struct {
+	contract *bind.BoundContract
+}
+
+// NewAggregatorV3 binds the price feed.
+func NewAggregatorV3(address common.Address, caller bind.ContractCaller) (*AggregatorV3, error) {
+	contract, err := bindABI(AggregatorV3ABI, address, caller)
+	if err != nil {
+		return nil, err
+	}
+	return &AggregatorV3{contract: contract}, nil
+}
+
+// Decimals returns the decimals of the answers.
+func (agg *AggregatorV3) Decimals(ctx context.Context) (int, error) {
+	var out []interface{}
+	if err := agg.contract.Call(&bind.CallOpts{Context: ctx}, &out, "decimals"); err != nil {
+		return 0, err
+	}
+	decimals, ok := out[0].(uint8)
+	if !ok {
+		return 0, errors.New("unexpected decimals type")
+	}
+	return int(decimals), nil
+}
+
+// LatestRoundData returns the latest answer.
+func (agg *AggregatorV3) LatestRoundData(ctx context.Context) (*RoundData, error) {
+	var out []interface{}
+	if err := agg.contract.Call(&bind.CallOpts{Context: ctx}, &out, "latestRoundData"); err != nil {
+		return nil, err
+	}
+	if len(out) != 5 {
+		return nil, fmt.Errorf("unexpected number of outputs: %d", len(out))
+	}
+	roundID, ok1 := out[0].(*big.Int)
+	answer, ok2 := out[1].(*big.Int)
+	updatedAt, ok3 := out[3].(*big.Int)
+	answeredInRound, ok4 := out[4].(*big.Int)
+	if !(ok1 && ok2 && ok3 && ok4) {
+		return nil, errors.New("unexpected output types")
+	}
+	return &RoundData{
+		RoundID:         roundID,
+		Answer:          answer,
+		UpdatedAt:       time.Unix(updatedAt.Int64(), 0),
+		AnsweredInRound: answeredInRound,
+	}, nil
+}
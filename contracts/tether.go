@@ -0,0 +1,48 @@
+package contracts
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// TetherSupplyABI is the ABI of the USDT supply events. USDT emits them instead of the
+// transfers from and to the zero address when the owner issues and redeems tokens.
+const TetherSupplyABI = `[
+	{"anonymous":false,"inputs":[{"indexed":false,"name":"amount","type":"uint256"}],"name":"Issue","type":"event"},
+	{"anonymous":false,"inputs":[{"indexed":false,"name":"amount","type":"uint256"}],"name":"Redeem","type":"event"}
+]`
+
+// Tether supply event topics
+var (
+	TetherIssueTopic  = crypto.Keccak256Hash([]byte("Issue(uint256)"))
+	TetherRedeemTopic = crypto.Keccak256Hash([]byte("Redeem(uint256)"))
+)
+
+// TetherSupplyEvent is an Issue or a Redeem event.
+type TetherSupplyEvent struct {
+	Amount *big.Int
+	Raw    types.Log
+}
+
+// BindTetherSupply binds the USDT supply events of the contract.
+func BindTetherSupply(address common.Address) (*bind.BoundContract, error) {
+	return bindABI(TetherSupplyABI, address, nil)
+}
+
+// UnpackTetherSupplyEvent unpacks the Issue or the Redeem event.
+func UnpackTetherSupplyEvent(contract *bind.BoundContract, log *types.Log) (*TetherSupplyEvent, error) {
+	var event TetherSupplyEvent
+	name := "Issue"
+	if len(log.Topics) > 0 && log.Topics[0] == TetherRedeemTopic {
+		name = "Redeem"
+	}
+	if err := contract.UnpackLog(&event, name, *log); err != nil {
+		return nil, err
+	}
+	event.Raw = *log
+	return &event, nil
+}
@@ -0,0 +1,94 @@
+package contracts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// IERC20MetadataABI is the ABI of the optional ERC20 metadata functions.
+const IERC20MetadataABI = `[
+	{"inputs":[],"name":"name","outputs":[{"name":"","type":"string"}],"stateMutability":"view","type":"function"},
+	{"inputs":[],"name":"symbol","outputs":[{"name":"","type":"string"}],"stateMutability":"view","type":"function"},
+	{"inputs":[],"name":"decimals","outputs":[{"name":"","type":"uint8"}],"stateMutability":"view","type":"function"}
+]`
+
+// IERC20Bytes32MetadataABI is the ABI of the non-standard tokens (e.g. MKR) which return bytes32 names and symbols.
+const IERC20Bytes32MetadataABI = `[
+	{"inputs":[],"name":"name","outputs":[{"name":"","type":"bytes32"}],"stateMutability":"view","type":"function"},
+	{"inputs":[],"name":"symbol","outputs":[{"name":"","type":"bytes32"}],"stateMutability":"view","type":"function"}
+]`
+
+// TokenMetadata contains the ERC20 token metadata.
+type TokenMetadata struct {
+	Address  string `json:"address"`
+	Name     string `json:"name"`
+	Symbol   string `json:"symbol"`
+	Decimals int    `json:"decimals"`
+}
+
+// ReadTokenMetadata calls name(), symbol() and decimals() of the token. The name is optional.
+func ReadTokenMetadata(ctx context.Context, caller bind.ContractCaller, address common.Address) (*TokenMetadata, error) {
+	contract, err := bindABI(IERC20MetadataABI, address, caller)
+	if err != nil {
+		return nil, err
+	}
+	bytes32Contract, err := bindABI(IERC20Bytes32MetadataABI, address, caller)
+	if err != nil {
+		return nil, err
+	}
+	opts := &bind.CallOpts{Context: ctx}
+
+	token := &TokenMetadata{Address: address.Hex()}
+	if token.Symbol, err = callString(opts, contract, bytes32Contract, "symbol"); err != nil {
+		return nil, fmt.Errorf("failed to get the symbol: %v", err)
+	}
+	// Some tokens do not implement name() so it is fine to leave it empty.
+	token.Name, _ = callString(opts, contract, bytes32Contract, "name")
+
+	var out []interface{}
+	if err := contract.Call(opts, &out, "decimals"); err != nil {
+		return nil, fmt.Errorf("failed to get the decimals: %v", err)
+	}
+	decimals, ok := out[0].(uint8)
+	if !ok {
+		return nil, errors.New("unexpected decimals type")
+	}
+	token.Decimals = int(decimals)
+
+	return token, nil
+}
+
+func bindABI(contractABI string, address common.Address, caller bind.ContractCaller) (*bind.BoundContract, error) {
+	parsed, err := abi.JSON(strings.NewReader(contractABI))
+	if err != nil {
+		return nil, err
+	}
+	return bind.NewBoundContract(address, parsed, caller, nil, nil), nil
+}
+
+// callString calls a method which returns a string and retries with the bytes32 ABI if the result cannot be unpacked.
+func callString(opts *bind.CallOpts, contract, bytes32Contract *bind.BoundContract, method string) (string, error) {
+	var out []interface{}
+	err := contract.Call(opts, &out, method)
+	if err == nil {
+		if s, ok := out[0].(string); ok {
+			return s, nil
+		}
+	}
+
+	out = nil
+	if err := bytes32Contract.Call(opts, &out, method); err != nil {
+		return "", err
+	}
+	b, ok := out[0].([32]byte)
+	if !ok {
+		return "", errors.New("unexpected bytes32 type")
+	}
+	return strings.TrimRight(string(b[:]), "\x00"), nil
+}
@@ -0,0 +1,51 @@
+package agent
+
+import (
+	"strings"
+	"time"
+)
+
+// Mute suppresses the findings about an address until it expires.
+type Mute struct {
+	Address string    `json:"address"`
+	Until   time.Time `json:"until"`
+	By      string    `json:"by"`
+}
+
+// Ack records that someone has taken care of a finding. It suppresses the next findings
+// of the agent with the same subject until it expires.
+type Ack struct {
+	AgentID string    `json:"agentId"`
+	Subject string    `json:"subject"`
+	TxHash  string    `json:"txHash"`
+	By      string    `json:"by"`
+	At      time.Time `json:"at"`
+}
+
+// MinAmount suppresses the findings of an agent below the amount in token units.
+type MinAmount struct {
+	AgentID string `json:"agentId"`
+	Amount  string `json:"amount"`
+	By      string `json:"by"`
+}
+
+// ActionRepository keeps the actions taken on the findings, which the agents
+// check before notifying.
+type ActionRepository interface {
+	SaveMute(*Mute) error
+	GetMute(address string) (*Mute, error)
+	SaveAck(*Ack) error
+	GetAck(agentID, subject string) (*Ack, error)
+	SaveMinAmount(*MinAmount) error
+	GetMinAmount(agentID string) (*MinAmount, error)
+}
+
+// AckSubject returns what the finding is about, which is the sender and the receiver.
+// It is empty if the finding is not about any address.
+func AckSubject(finding *Finding) string {
+	from, to := finding.Metadata[MetadataFrom], finding.Metadata[MetadataTo]
+	if len(from) == 0 && len(to) == 0 {
+		return ""
+	}
+	return strings.ToLower(from + "->" + to)
+}
@@ -0,0 +1,113 @@
+package agent
+
+import (
+	"context"
+	"sort"
+)
+
+// Severity is the importance of a finding.
+type Severity string
+
+// Severity levels
+const (
+	SeverityInfo     Severity = "info"
+	SeverityLow      Severity = "low"
+	SeverityMedium   Severity = "medium"
+	SeverityHigh     Severity = "high"
+	SeverityCritical Severity = "critical"
+)
+
+var severityRanks = map[Severity]int{
+	SeverityInfo:     1,
+	SeverityLow:      2,
+	SeverityMedium:   3,
+	SeverityHigh:     4,
+	SeverityCritical: 5,
+}
+
+// Rank returns the order of the severity. Unknown severities rank zero.
+func (s Severity) Rank() int {
+	return severityRanks[s]
+}
+
+// Common metadata keys which the notifiers know how to render.
+const (
+	// MetadataFrom is the sender address of the transfer.
+	MetadataFrom = "from"
+	// MetadataTo is the receiver address of the transfer.
+	MetadataTo = "to"
+	// MetadataAmount is the exact decimal token amount.
+	MetadataAmount = "amount"
+	// MetadataSymbol is the symbol of the transferred token.
+	MetadataSymbol = "symbol"
+	// MetadataToken is the address of the transferred token contract.
+	MetadataToken = "token"
+	// MetadataAmountRaw is the amount in the token base units.
+	MetadataAmountRaw = "amountRaw"
+	// MetadataDecimals is the number of the token decimals.
+	MetadataDecimals = "decimals"
+	// MetadataAmountUSD is the amount in USD when there is a price feed.
+	MetadataAmountUSD = "amountUsd"
+
+	// MetadataFromLabel is the name of the labeled sender address.
+	MetadataFromLabel = "fromLabel"
+	// MetadataToLabel is the name of the labeled receiver address.
+	MetadataToLabel = "toLabel"
+	// MetadataFromCategory is the category of the labeled sender address.
+	MetadataFromCategory = "fromCategory"
+	// MetadataToCategory is the category of the labeled receiver address.
+	MetadataToCategory = "toCategory"
+
+	// MetadataWatchlist is the comma separated names of the matching watchlists.
+	MetadataWatchlist = "watchlist"
+)
+
+var knownMetadataKeys = []string{
+	MetadataFrom, MetadataTo, MetadataAmount, MetadataAmountUSD, MetadataSymbol, MetadataToken,
+}
+
+// Finding is the result of an agent detecting something worth notifying about.
+type Finding struct {
+	AgentID     string            `json:"agentId"`
+	AlertType   string            `json:"alertType"`
+	Severity    Severity          `json:"severity"`
+	ChainID     uint64            `json:"chainId"`
+	BlockNumber uint64            `json:"blockNumber"`
+	BlockHash   string            `json:"blockHash"`
+	TxHash      string            `json:"txHash"`
+	LogIndex    uint              `json:"logIndex"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+}
+
+// MetadataKeys returns the metadata keys in rendering order: the common keys
+// come first and the rest are sorted.
+func (f *Finding) MetadataKeys() []string {
+	var keys []string
+	for _, key := range knownMetadataKeys {
+		if _, ok := f.Metadata[key]; ok {
+			keys = append(keys, key)
+		}
+	}
+	var rest []string
+	for key := range f.Metadata {
+		if !isKnownMetadataKey(key) {
+			rest = append(rest, key)
+		}
+	}
+	sort.Strings(rest)
+	return append(keys, rest...)
+}
+
+func isKnownMetadataKey(key string) bool {
+	for _, known := range knownMetadataKeys {
+		if key == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Notifier sends notifications about findings.
+type Notifier interface {
+	Notify(context.Context, *Finding) error
+}
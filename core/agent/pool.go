@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
 
 	"github.com/canercidam/large-tx-detector/core"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -14,6 +15,12 @@ var (
 	ErrIgnore = errors.New("agent ignores the transaction")
 )
 
+// Config vars
+var (
+	// BlockAgentMaxAttempts is the number of times a block agent can fail a block before the block is skipped.
+	BlockAgentMaxAttempts = 3
+)
+
 // Agent is a transaction handler with iteration capabilities.
 type Agent interface {
 	ID() string
@@ -23,6 +30,12 @@ type Agent interface {
 	core.TransactionHandler
 }
 
+// BlockAgent is a block handler.
+type BlockAgent interface {
+	ID() string
+	core.BlockHandler
+}
+
 // AgentRepository manages agent operations i.e. tx handling per agent.
 type AgentRepository interface {
 	SaveOperation(*Operation) error
@@ -31,8 +44,14 @@ type AgentRepository interface {
 
 // Pool aggregates registered agents and handles a transaction for each.
 type Pool struct {
-	agents []Agent
-	repo   AgentRepository
+	agents      []Agent
+	blockAgents []BlockAgent
+	repo        AgentRepository
+
+	// The block agent progress of the current block.
+	currentBlock  uint64
+	blockDone     map[string]bool
+	blockAttempts map[string]int
 }
 
 // NewPool creates a new pool.
@@ -45,6 +64,48 @@ func (pool *Pool) AddAgent(agent Agent) {
 	pool.agents = append(pool.agents, agent)
 }
 
+// AddBlockAgent registers an agent to handle any incoming block after its transactions.
+func (pool *Pool) AddBlockAgent(agent BlockAgent) {
+	pool.blockAgents = append(pool.blockAgents, agent)
+}
+
+// HandleBlock implements core.BlockHandler. The block is retried when an agent fails, without
+// the agents which have already handled it. An agent which fails BlockAgentMaxAttempts times
+// skips the block so that it cannot stall the other agents.
+func (pool *Pool) HandleBlock(ctx context.Context, block *types.Block) error {
+	if pool.blockDone == nil || pool.currentBlock != block.NumberU64() {
+		pool.currentBlock = block.NumberU64()
+		pool.blockDone = make(map[string]bool)
+		pool.blockAttempts = make(map[string]int)
+	}
+	var failed error
+	for _, agent := range pool.blockAgents {
+		if pool.blockDone[agent.ID()] {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+		err := agent.HandleBlock(ctx, block)
+		if err == nil {
+			pool.blockDone[agent.ID()] = true
+			continue
+		}
+		pool.blockAttempts[agent.ID()]++
+		if pool.blockAttempts[agent.ID()] >= BlockAgentMaxAttempts {
+			log.Printf("agent '%s' skipped block %d after %d attempts: %v", agent.ID(), block.NumberU64(), BlockAgentMaxAttempts, err)
+			pool.blockDone[agent.ID()] = true
+			continue
+		}
+		if failed == nil {
+			failed = fmt.Errorf("agent '%s' failed: %v", agent.ID(), err)
+		}
+	}
+	return failed
+}
+
 // HandleTransaction implements core.TransactionHandler.
 func (pool *Pool) HandleTransaction(ctx context.Context, block *types.Block, tx *types.Transaction) error {
 	for _, agent := range pool.agents {
@@ -89,7 +150,7 @@ func (pool *Pool) handleTxWithAgent(ctx context.Context, block *types.Block, tx
 			break
 		}
 		txErr = agent.HandleTransaction(ctx, block, tx)
-		if err != nil {
+		if txErr != nil {
 			break
 		}
 		op.State++
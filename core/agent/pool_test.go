@@ -0,0 +1,93 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// memOperations keeps the operations in memory.
+type memOperations struct {
+	ops map[string]*Operation
+}
+
+func (m *memOperations) SaveOperation(op *Operation) error {
+	saved := *op
+	m.ops[op.TxHash+"/"+op.AgentID] = &saved
+	return nil
+}
+
+func (m *memOperations) GetOperation(txHash, agentID string) (*Operation, error) {
+	op, ok := m.ops[txHash+"/"+agentID]
+	if !ok {
+		return nil, nil
+	}
+	copied := *op
+	return &copied, nil
+}
+
+// flakyAgent fails the first handlings of a tx.
+type flakyAgent struct {
+	id       string
+	failures int
+	handled  int
+	state    int
+}
+
+func (a *flakyAgent) ID() string                                 { return a.id }
+func (a *flakyAgent) Skip(*types.Block, *types.Transaction) bool { return false }
+func (a *flakyAgent) Init(op *Operation, tx *types.Transaction)  { a.state = op.State }
+
+func (a *flakyAgent) Next() bool {
+	a.state++
+	return a.state < 2
+}
+
+func (a *flakyAgent) HandleTransaction(ctx context.Context, block *types.Block, tx *types.Transaction) error {
+	if a.failures > 0 {
+		a.failures--
+		return errors.New("temporary failure")
+	}
+	a.handled++
+	return nil
+}
+
+func TestPoolRetriesFailedTransaction(t *testing.T) {
+	repo := &memOperations{ops: make(map[string]*Operation)}
+	first := &flakyAgent{id: "first"}
+	flaky := &flakyAgent{id: "flaky", failures: 1}
+	pool := NewPool(repo)
+	pool.AddAgent(first)
+	pool.AddAgent(flaky)
+
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(1)})
+	tx := types.NewTransaction(0, common.Address{}, big.NewInt(0), 21000, big.NewInt(1), nil)
+	ctx := context.Background()
+
+	if err := pool.HandleTransaction(ctx, block, tx); err == nil {
+		t.Fatal("expected the failure of the agent")
+	}
+	op, _ := repo.GetOperation(tx.Hash().String(), "flaky")
+	if op == nil || op.Done {
+		t.Fatalf("expected the failed operation to be pending, got %+v", op)
+	}
+
+	// The retry handles the tx again only with the failed agent.
+	if err := pool.HandleTransaction(ctx, block, tx); err != nil {
+		t.Fatal(err)
+	}
+	if err := pool.HandleTransaction(ctx, block, tx); err != nil {
+		t.Fatal(err)
+	}
+	if first.handled != 1 || flaky.handled != 1 {
+		t.Fatalf("expected each agent to handle the tx once, got %d and %d", first.handled, flaky.handled)
+	}
+	op, _ = repo.GetOperation(tx.Hash().String(), "flaky")
+	if op == nil || !op.Done {
+		t.Fatalf("expected the operation to be done, got %+v", op)
+	}
+}
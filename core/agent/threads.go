@@ -0,0 +1,16 @@
+package agent
+
+import "time"
+
+// SlackThread is the first message of the related alerts in a channel.
+type SlackThread struct {
+	ChannelID string `json:"channelId"`
+	Key       string `json:"key"`
+	TS        string `json:"ts"`
+}
+
+// ThreadRepository keeps the Slack threads until they expire.
+type ThreadRepository interface {
+	GetSlackThread(channelID, key string) (*SlackThread, error)
+	SaveSlackThread(thread *SlackThread, ttl time.Duration) error
+}
@@ -26,6 +26,12 @@ type TransactionHandler interface {
 	HandleTransaction(context.Context, *types.Block, *types.Transaction) error
 }
 
+// BlockHandler handles a block after its transactions. The transaction handlers can
+// optionally implement it.
+type BlockHandler interface {
+	HandleBlock(context.Context, *types.Block) error
+}
+
 // BlockCounter keeps track of the blocks we need to process.
 type BlockCounter interface {
 	GetLatestBlock() (uint64, error)
@@ -98,5 +104,10 @@ func (blCons *BlockConsumer) consumeAllTxs(ctx context.Context, block *types.Blo
 			return fmt.Errorf("failed to handle transaction %s: %v", tx.Hash(), err)
 		}
 	}
+	if blockHandler, ok := blCons.txHandler.(BlockHandler); ok {
+		if err := blockHandler.HandleBlock(ctx, block); err != nil {
+			return fmt.Errorf("failed to handle block %d: %v", block.NumberU64(), err)
+		}
+	}
 	return nil
 }
@@ -0,0 +1,70 @@
+package format
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// FormatUnits converts the amount in the token base units to the exact decimal
+// token amount without the trailing zeros, e.g. 1234567890000 with 6 decimals is 1234567.89.
+func FormatUnits(raw *big.Int, decimals int) string {
+	s := new(big.Int).Abs(raw).String()
+	sign := ""
+	if raw.Sign() < 0 {
+		sign = "-"
+	}
+	if decimals <= 0 {
+		return sign + s
+	}
+	if len(s) <= decimals {
+		s = strings.Repeat("0", decimals-len(s)+1) + s
+	}
+	intPart, fracPart := s[:len(s)-decimals], strings.TrimRight(s[len(s)-decimals:], "0")
+	if len(fracPart) == 0 {
+		return sign + intPart
+	}
+	return sign + intPart + "." + fracPart
+}
+
+// ParseUnits converts the decimal token amount to the base units.
+func ParseUnits(amount string, decimals int) (*big.Int, error) {
+	r, ok := new(big.Rat).SetString(amount)
+	if !ok {
+		return nil, fmt.Errorf("invalid amount '%s'", amount)
+	}
+	exp := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+	r.Mul(r, new(big.Rat).SetInt(exp))
+	if !r.IsInt() {
+		return nil, fmt.Errorf("amount '%s' has more than %d decimals", amount, decimals)
+	}
+	return new(big.Int).Set(r.Num()), nil
+}
+
+// USD formats the decimal dollar amount like $1,234.56.
+func USD(amount string) string {
+	if len(amount) == 0 {
+		return ""
+	}
+	return "$" + GroupThousands(amount)
+}
+
+// GroupThousands adds the thousands separators to a decimal amount, e.g. 1234567.89 is 1,234,567.89.
+func GroupThousands(amount string) string {
+	sign := ""
+	if strings.HasPrefix(amount, "-") {
+		sign, amount = "-", amount[1:]
+	}
+	intPart, fracPart := amount, ""
+	if i := strings.IndexByte(amount, '.'); i >= 0 {
+		intPart, fracPart = amount[:i], amount[i:]
+	}
+	var b strings.Builder
+	for i, r := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(r)
+	}
+	return sign + b.String() + fracPart
+}
@@ -0,0 +1,47 @@
+package format
+
+import (
+	"fmt"
+
+	"github.com/canercidam/large-tx-detector/config"
+	"github.com/canercidam/large-tx-detector/core/agent"
+)
+
+// TxURL makes a link to the transaction page in the block explorer.
+func TxURL(txHash string) string {
+	return fmt.Sprintf("%s/tx/%s", config.Vars.EtherscanBaseURL, txHash)
+}
+
+// BlockURL makes a link to the block page in the block explorer.
+func BlockURL(number uint64) string {
+	return fmt.Sprintf("%s/block/%d", config.Vars.EtherscanBaseURL, number)
+}
+
+// FindingURL links to the transaction of the finding, or to the block if the finding is about a block.
+func FindingURL(finding *agent.Finding) string {
+	if len(finding.TxHash) == 0 {
+		return BlockURL(finding.BlockNumber)
+	}
+	return TxURL(finding.TxHash)
+}
+
+// FindingRef is the tx hash of the finding, or the block if the finding is about a block.
+func FindingRef(finding *agent.Finding) string {
+	if len(finding.TxHash) == 0 {
+		return fmt.Sprintf("block %d", finding.BlockNumber)
+	}
+	return finding.TxHash
+}
+
+// AddressURL makes a link to the address page in the block explorer.
+func AddressURL(address string) string {
+	return fmt.Sprintf("%s/address/%s", config.Vars.EtherscanBaseURL, address)
+}
+
+// ShortAddress shortens a hex address or hash like 0x1234…cdef.
+func ShortAddress(address string) string {
+	if len(address) <= 12 {
+		return address
+	}
+	return address[:6] + "…" + address[len(address)-4:]
+}
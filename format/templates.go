@@ -0,0 +1,108 @@
+package format
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"text/template"
+
+	"github.com/canercidam/large-tx-detector/core/agent"
+)
+
+// AnyAgent is the key of the template which is used for the agents without their own.
+const AnyAgent = "*"
+
+// Funcs returns the helper functions of the templates.
+func Funcs() template.FuncMap {
+	return template.FuncMap{
+		"txURL":      TxURL,
+		"addressURL": AddressURL,
+		"blockURL":   BlockURL,
+		"findingURL": FindingURL,
+		"findingRef": FindingRef,
+		"short":      ShortAddress,
+		"amount":     Amount,
+		"thousands":  GroupThousands,
+		"usd":        USD,
+		"label":      Label,
+		"category":   Category,
+		"markdownV2": EscapeMarkdownV2,
+		"upper":      strings.ToUpper,
+		"lower":      strings.ToLower,
+	}
+}
+
+// Amount returns the finding amount with the thousands separators and the symbol.
+func Amount(finding *agent.Finding) string {
+	return strings.TrimSpace(fmt.Sprintf(
+		"%s %s", GroupThousands(finding.Metadata[agent.MetadataAmount]), finding.Metadata[agent.MetadataSymbol],
+	))
+}
+
+// Label returns the label of an address in the finding, e.g. label . "from".
+func Label(finding *agent.Finding, address string) string {
+	return finding.Metadata[address+"Label"]
+}
+
+// Category returns the label category of an address in the finding, e.g. category . "to".
+func Category(finding *agent.Finding, address string) string {
+	return finding.Metadata[address+"Category"]
+}
+
+// Templates renders the findings with the user defined templates by notifier and agent.
+type Templates struct {
+	templates map[string]map[string]*template.Template
+}
+
+// LoadTemplates reads the templates from a JSON file which maps the notifier names
+// to the templates by agent ID, e.g. {"slack": {"*": "...", "my-agent": "..."}}.
+func LoadTemplates(path string) (*Templates, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var sources map[string]map[string]string
+	if err := json.Unmarshal(b, &sources); err != nil {
+		return nil, fmt.Errorf("failed to decode the templates: %v", err)
+	}
+	return ParseTemplates(sources)
+}
+
+// ParseTemplates parses the template sources by notifier and agent.
+func ParseTemplates(sources map[string]map[string]string) (*Templates, error) {
+	t := &Templates{templates: make(map[string]map[string]*template.Template)}
+	for notifier, byAgent := range sources {
+		t.templates[notifier] = make(map[string]*template.Template)
+		for agentID, src := range byAgent {
+			tmpl, err := template.New(notifier + "/" + agentID).Funcs(Funcs()).Parse(src)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse the template of %s for %s: %v", notifier, agentID, err)
+			}
+			t.templates[notifier][agentID] = tmpl
+		}
+	}
+	return t, nil
+}
+
+// Render renders the finding with the template of the notifier for the agent.
+// It returns false if there is no such template so that the notifier can use its default format.
+func (t *Templates) Render(notifier string, finding *agent.Finding) (string, bool, error) {
+	if t == nil {
+		return "", false, nil
+	}
+	byAgent := t.templates[notifier]
+	tmpl, ok := byAgent[finding.AgentID]
+	if !ok {
+		tmpl, ok = byAgent[AnyAgent]
+	}
+	if !ok {
+		return "", false, nil
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, finding); err != nil {
+		return "", false, fmt.Errorf("failed to render the %s template: %v", notifier, err)
+	}
+	return buf.String(), true, nil
+}
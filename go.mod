@@ -3,6 +3,7 @@ module github.com/canercidam/large-tx-detector
 go 1.15
 
 require (
+	github.com/antonmedv/expr v1.9.0
 	github.com/dgraph-io/badger/v3 v3.2011.1
 	github.com/ethereum/go-ethereum v1.10.2
 	github.com/kelseyhightower/envconfig v1.4.0
@@ -0,0 +1,153 @@
+package labels
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/canercidam/large-tx-detector/core/agent"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Categories
+const (
+	CategoryExchange   = "exchange"
+	CategoryBridge     = "bridge"
+	CategoryDeFi       = "defi"
+	CategoryTeam       = "team"
+	CategorySanctioned = "sanctioned"
+)
+
+// Label describes who owns an address.
+type Label struct {
+	Address  string `json:"address"`
+	Name     string `json:"label"`
+	Category string `json:"category"`
+	Source   string `json:"source"`
+}
+
+// Repository stores the labels.
+type Repository interface {
+	SaveLabel(*Label) error
+	GetLabel(address string) (*Label, error)
+}
+
+// Registry looks up the address labels.
+type Registry struct {
+	repo Repository
+}
+
+// NewRegistry creates a new registry.
+func NewRegistry(repo Repository) *Registry {
+	return &Registry{repo: repo}
+}
+
+// Load reads the labels from the CSV or JSON files and saves them. The CSV files
+// must have the address, label, category and source columns. The file name is the default source.
+func (reg *Registry) Load(paths ...string) (int, error) {
+	var count int
+	for _, path := range paths {
+		labels, err := readFile(path)
+		if err != nil {
+			return count, fmt.Errorf("failed to read the labels from %s: %v", path, err)
+		}
+		for _, label := range labels {
+			if !common.IsHexAddress(label.Address) {
+				return count, fmt.Errorf("invalid address '%s' in %s", label.Address, path)
+			}
+			label.Address = common.HexToAddress(label.Address).Hex()
+			label.Category = strings.ToLower(label.Category)
+			if len(label.Source) == 0 {
+				label.Source = filepath.Base(path)
+			}
+			if err := reg.repo.SaveLabel(label); err != nil {
+				return count, fmt.Errorf("failed to save the label of %s: %v", label.Address, err)
+			}
+			count++
+		}
+	}
+	return count, nil
+}
+
+func readFile(path string) ([]*Label, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		var labels []*Label
+		if err := json.NewDecoder(f).Decode(&labels); err != nil {
+			return nil, err
+		}
+		return labels, nil
+	}
+	return readCSV(f)
+}
+
+func readCSV(r io.Reader) ([]*Label, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	var labels []*Label
+	for i, record := range records {
+		// Skip the header.
+		if i == 0 && strings.EqualFold(record[0], "address") {
+			continue
+		}
+		if len(record) < 2 {
+			return nil, fmt.Errorf("line %d: expected at least the address and the label", i+1)
+		}
+		label := &Label{Address: record[0], Name: record[1]}
+		if len(record) > 2 {
+			label.Category = record[2]
+		}
+		if len(record) > 3 {
+			label.Source = record[3]
+		}
+		labels = append(labels, label)
+	}
+	return labels, nil
+}
+
+// Lookup finds the label of the address. It returns nil if the address does not have a label.
+func (reg *Registry) Lookup(address string) (*Label, error) {
+	return reg.repo.GetLabel(address)
+}
+
+// Annotate attaches the labels of the from and to addresses to the finding.
+func (reg *Registry) Annotate(finding *agent.Finding) error {
+	sides := []struct {
+		addressKey, labelKey, categoryKey string
+	}{
+		{agent.MetadataFrom, agent.MetadataFromLabel, agent.MetadataFromCategory},
+		{agent.MetadataTo, agent.MetadataToLabel, agent.MetadataToCategory},
+	}
+	for _, side := range sides {
+		address, ok := finding.Metadata[side.addressKey]
+		if !ok {
+			continue
+		}
+		label, err := reg.Lookup(address)
+		if err != nil {
+			return fmt.Errorf("failed to get the label of %s: %v", address, err)
+		}
+		if label == nil {
+			continue
+		}
+		finding.Metadata[side.labelKey] = label.Name
+		if len(label.Category) > 0 {
+			finding.Metadata[side.categoryKey] = label.Category
+		}
+	}
+	return nil
+}
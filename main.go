@@ -2,14 +2,20 @@ package main
 
 import (
 	"context"
+	"expvar"
 	"log"
+	"time"
 
 	"github.com/canercidam/large-tx-detector/agents"
 	"github.com/canercidam/large-tx-detector/agents/notifier"
 	"github.com/canercidam/large-tx-detector/clients"
 	"github.com/canercidam/large-tx-detector/core"
 	"github.com/canercidam/large-tx-detector/core/agent"
+	"github.com/canercidam/large-tx-detector/format"
+	"github.com/canercidam/large-tx-detector/labels"
 	"github.com/canercidam/large-tx-detector/repository/badgerrepo"
+	"github.com/canercidam/large-tx-detector/rules"
+	"github.com/canercidam/large-tx-detector/server"
 
 	"github.com/canercidam/large-tx-detector/config"
 )
@@ -28,18 +34,245 @@ func main() {
 	if err != nil {
 		log.Panicf("failed to init the rpc client: %v", err)
 	}
+	chainID, err := rpcClient.ChainID(ctx)
+	if err != nil {
+		log.Panicf("failed to get the chain id: %v", err)
+	}
+
+	// Initialize the notifier. Findings go to Slack unless routing rules are configured.
+	var templates *format.Templates
+	if len(config.Vars.NotificationTemplatesPath) > 0 {
+		templates, err = format.LoadTemplates(config.Vars.NotificationTemplatesPath)
+		if err != nil {
+			log.Panicf("failed to load the notification templates: %v", err)
+		}
+	}
+	var largeTxNotifier agent.Notifier
+	if len(config.Vars.NotificationRoutesPath) == 0 {
+		slackConf := notifier.DefaultSlackConfig()
+		slackConf.Threads = repo
+		slackConf.Templates = templates
+		largeTxNotifier, err = notifier.NewSlackNotifier(ctx, slackConf)
+		if err != nil {
+			log.Panicf("failed to init the slack notifier: %v", err)
+		}
+	} else {
+		routerConf, err := notifier.LoadRouterConfig(config.Vars.NotificationRoutesPath)
+		if err != nil {
+			log.Panicf("failed to load the notification routes: %v", err)
+		}
+		routerConf.SlackThreads = repo
+		routerConf.Templates = templates
+		largeTxNotifier, err = notifier.NewRouter(ctx, routerConf)
+		if err != nil {
+			log.Panicf("failed to init the notification router: %v", err)
+		}
+	}
+
+	// Load the address labels.
+	labelRegistry := labels.NewRegistry(repo)
+	if len(config.Vars.LabelsPaths) > 0 {
+		count, err := labelRegistry.Load(config.Vars.LabelsPaths...)
+		if err != nil {
+			log.Panicf("failed to load the labels: %v", err)
+		}
+		log.Printf("loaded %d address labels", count)
+	}
+
+	// Seed the watchlist. The entries can be edited at runtime through the HTTP API.
+	if len(config.Vars.WatchlistPath) > 0 {
+		count, err := agents.LoadWatchlist(repo, config.Vars.WatchlistPath)
+		if err != nil {
+			log.Panicf("failed to load the watchlist: %v", err)
+		}
+		log.Printf("loaded %d watchlist entries", count)
+	}
+
+	var ignoreList *agents.IgnoreList
+	if len(config.Vars.IgnoreRulesPath) > 0 {
+		ignoreList, err = agents.LoadIgnoreList(config.Vars.IgnoreRulesPath)
+		if err != nil {
+			log.Panicf("failed to load the ignore rules: %v", err)
+		}
+	}
+
+	severityTiers, err := agents.ParseSeverityTiers(config.Vars.WatchedTokenSeverityTiers)
+	if err != nil {
+		log.Panicf("failed to parse the severity tiers: %v", err)
+	}
+	if agents.HasUSDTiers(severityTiers) && len(config.Vars.WatchedTokenPriceFeed) == 0 {
+		log.Panic("the USD severity tiers need WATCHED_TOKEN_PRICE_FEED")
+	}
+	var ruleEngine *rules.Engine
+	if len(config.Vars.RulesPath) > 0 {
+		ruleEngine, err = rules.Load(config.Vars.RulesPath, rpcClient)
+		if err != nil {
+			log.Panicf("failed to load the rules: %v", err)
+		}
+	}
 
 	// Initialize the agents.
+	var priceFeed *agents.PriceFeed
+	if len(config.Vars.WatchedTokenPriceFeed) > 0 {
+		priceFeed, err = agents.NewPriceFeed(
+			rpcClient, config.Vars.WatchedTokenPriceFeed, time.Second*time.Duration(config.Vars.PriceFeedMaxAgeSeconds),
+		)
+		if err != nil {
+			log.Panicf("failed to init the price feed: %v", err)
+		}
+	}
 	largeTxDet := agents.NewLargeTxDetector(&agents.LTDConfig{
-		AgentID:      "default-agent",
-		TokenAddress: config.Vars.WatchedTokenAddress,
-		Symbol:       config.Vars.WatchedTokenSymbol,
-		Threshold:    config.Vars.WatchedTokenThreshold,
-		Notifier:     notifier.NewSlackNotifier(),
-		Client:       rpcClient,
+		AgentID:       "default-agent",
+		ChainID:       chainID.Uint64(),
+		TokenAddress:  config.Vars.WatchedTokenAddress,
+		Symbol:        config.Vars.WatchedTokenSymbol,
+		Decimals:      config.Vars.WatchedTokenDecimals,
+		Threshold:     config.Vars.WatchedTokenThreshold,
+		USDThreshold:  config.Vars.WatchedTokenUSDThreshold,
+		SeverityTiers: severityTiers,
+		MintThreshold: config.Vars.WatchedTokenMintThreshold,
+		BurnThreshold: config.Vars.WatchedTokenBurnThreshold,
+		PriceFeed:     priceFeed,
+		Notifier:      largeTxNotifier,
+		Client:        rpcClient,
+		Actions:       repo,
+		Templates:     templates,
+		Tokens:        repo,
+		Labels:        labelRegistry,
+		Watchlist:     repo,
+		Ignore:        ignoreList,
+		Rules:         ruleEngine,
 	})
 	agentPool := agent.NewPool(repo)
 	agentPool.AddAgent(largeTxDet)
+	if len(config.Vars.AggregateBy) > 0 {
+		aggregator, err := agents.NewTransferAggregator(&agents.AggregatorConfig{
+			AgentID:       "aggregate-agent",
+			ChainID:       chainID.Uint64(),
+			TokenAddress:  config.Vars.WatchedTokenAddress,
+			Symbol:        config.Vars.WatchedTokenSymbol,
+			Decimals:      config.Vars.WatchedTokenDecimals,
+			GroupBy:       config.Vars.AggregateBy,
+			WindowBlocks:  config.Vars.AggregateWindowBlocks,
+			WindowSeconds: config.Vars.AggregateWindowSeconds,
+			Threshold:     config.Vars.AggregateThreshold,
+			Notifier:      largeTxNotifier,
+			Templates:     templates,
+			Client:        rpcClient,
+			Windows:       repo,
+			Tokens:        repo,
+			Labels:        labelRegistry,
+			Ignore:        ignoreList,
+			Rules:         ruleEngine,
+			Actions:       repo,
+		})
+		if err != nil {
+			log.Panicf("failed to init the transfer aggregator: %v", err)
+		}
+		agentPool.AddAgent(aggregator)
+	}
+	if config.Vars.ApprovalMonitor {
+		approvalMon, err := agents.NewApprovalMonitor(&agents.ApprovalConfig{
+			AgentID:          "approval-agent",
+			ChainID:          chainID.Uint64(),
+			TokenAddress:     config.Vars.WatchedTokenAddress,
+			Symbol:           config.Vars.WatchedTokenSymbol,
+			Decimals:         config.Vars.WatchedTokenDecimals,
+			Owners:           config.Vars.ApprovalWatchedAddresses,
+			Watchlist:        repo,
+			Threshold:        config.Vars.ApprovalThreshold,
+			NewSpenderBlocks: config.Vars.ApprovalNewSpenderBlocks,
+			Notifier:         largeTxNotifier,
+			Templates:        templates,
+			Client:           rpcClient,
+			Tokens:           repo,
+			Labels:           labelRegistry,
+			Ignore:           ignoreList,
+			Rules:            ruleEngine,
+			Actions:          repo,
+		})
+		if err != nil {
+			log.Panicf("failed to init the approval monitor: %v", err)
+		}
+		agentPool.AddAgent(approvalMon)
+	}
+	if config.Vars.SupplyCheckIntervalBlocks > 0 {
+		supplyMon, err := agents.NewSupplyMonitor(&agents.SupplyConfig{
+			AgentID:          "supply-agent",
+			ChainID:          chainID.Uint64(),
+			TokenAddress:     config.Vars.WatchedTokenAddress,
+			Symbol:           config.Vars.WatchedTokenSymbol,
+			Decimals:         config.Vars.WatchedTokenDecimals,
+			IntervalBlocks:   config.Vars.SupplyCheckIntervalBlocks,
+			Threshold:        config.Vars.SupplyChangeThreshold,
+			PercentThreshold: config.Vars.SupplyChangePercent,
+			Notifier:         largeTxNotifier,
+			Templates:        templates,
+			Client:           rpcClient,
+			Snapshots:        repo,
+			Tokens:           repo,
+			Ignore:           ignoreList,
+			Rules:            ruleEngine,
+			Actions:          repo,
+		})
+		if err != nil {
+			log.Panicf("failed to init the supply monitor: %v", err)
+		}
+		agentPool.AddBlockAgent(supplyMon)
+	}
+	if config.Vars.AnomalyZScore > 0 {
+		anomalyDet, err := agents.NewAnomalyDetector(&agents.AnomalyConfig{
+			AgentID:             "anomaly-agent",
+			ChainID:             chainID.Uint64(),
+			TokenAddress:        config.Vars.WatchedTokenAddress,
+			Symbol:              config.Vars.WatchedTokenSymbol,
+			Decimals:            config.Vars.WatchedTokenDecimals,
+			Alpha:               config.Vars.AnomalyAlpha,
+			ZScoreThreshold:     config.Vars.AnomalyZScore,
+			WarmupSamples:       config.Vars.AnomalyWarmupSamples,
+			BackfillChunkBlocks: config.Vars.AnomalyBackfillChunk,
+			Notifier:            largeTxNotifier,
+			Templates:           templates,
+			Client:              rpcClient,
+			Stats:               repo,
+			Tokens:              repo,
+			Labels:              labelRegistry,
+			Ignore:              ignoreList,
+			Rules:               ruleEngine,
+			Actions:             repo,
+		})
+		if err != nil {
+			log.Panicf("failed to init the anomaly detector: %v", err)
+		}
+		if config.Vars.AnomalyBackfillBlocks > 0 {
+			latestBlock, err := rpcClient.BlockNumber(ctx)
+			if err != nil {
+				log.Panicf("failed to get the latest block: %v", err)
+			}
+			var fromBlock uint64
+			if latestBlock > config.Vars.AnomalyBackfillBlocks {
+				fromBlock = latestBlock - config.Vars.AnomalyBackfillBlocks
+			}
+			if err := anomalyDet.Backfill(ctx, fromBlock, latestBlock); err != nil {
+				log.Panicf("failed to backfill the anomaly detector: %v", err)
+			}
+		}
+		agentPool.AddAgent(anomalyDet)
+	}
+
+	// Initialize the HTTP endpoints.
+	httpServer := server.New(config.Vars.HTTPAddress)
+	if len(config.Vars.SlackSigningSecret) > 0 {
+		httpServer.Handle("/slack/actions", notifier.NewSlackActionHandler(config.Vars.SlackSigningSecret, repo))
+	}
+	if len(config.Vars.APIToken) > 0 {
+		httpServer.Handle("/debug/vars", server.RequireToken(config.Vars.APIToken, expvar.Handler()))
+		httpServer.Handle("/api/watchlist", server.RequireToken(config.Vars.APIToken, agents.NewWatchlistHandler(repo)))
+		httpServer.Handle("/api/rules/test", server.RequireToken(config.Vars.APIToken, rules.NewTestHandler(rpcClient)))
+	} else {
+		log.Println("API_TOKEN is not set: /api and /debug endpoints are disabled")
+	}
+	httpServer.Start(ctx)
 
 	// Initialize the consumer, which listes to new blocks and lets agent pool handle.
 	blockConsumer := core.NewBlockConsumer(rpcClient, agentPool, repo)
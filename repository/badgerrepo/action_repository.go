@@ -0,0 +1,75 @@
+package badgerrepo
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/canercidam/large-tx-detector/core/agent"
+)
+
+// Config vars
+var (
+	AckTTL = time.Hour * 24
+)
+
+// SaveMute saves the mute until it expires.
+func (repo *Repository) SaveMute(mute *agent.Mute) error {
+	ttl := time.Until(mute.Until)
+	if ttl <= 0 {
+		return nil
+	}
+	return repo.set(muteKey(mute.Address), mute, ttl)
+}
+
+// GetMute gets the mute of the address if it has not expired.
+func (repo *Repository) GetMute(address string) (*agent.Mute, error) {
+	var mute agent.Mute
+	found, err := repo.get(muteKey(address), &mute)
+	if !found || err != nil {
+		return nil, err
+	}
+	return &mute, nil
+}
+
+// SaveAck saves the ack.
+func (repo *Repository) SaveAck(ack *agent.Ack) error {
+	return repo.set(ackKey(ack.AgentID, ack.Subject), ack, AckTTL)
+}
+
+// GetAck gets the ack of the subject if it has not expired.
+func (repo *Repository) GetAck(agentID, subject string) (*agent.Ack, error) {
+	var ack agent.Ack
+	found, err := repo.get(ackKey(agentID, subject), &ack)
+	if !found || err != nil {
+		return nil, err
+	}
+	return &ack, nil
+}
+
+// SaveMinAmount saves the min amount of the agent.
+func (repo *Repository) SaveMinAmount(minAmount *agent.MinAmount) error {
+	return repo.set(minAmountKey(minAmount.AgentID), minAmount, 0)
+}
+
+// GetMinAmount gets the min amount of the agent.
+func (repo *Repository) GetMinAmount(agentID string) (*agent.MinAmount, error) {
+	var minAmount agent.MinAmount
+	found, err := repo.get(minAmountKey(agentID), &minAmount)
+	if !found || err != nil {
+		return nil, err
+	}
+	return &minAmount, nil
+}
+
+func muteKey(address string) []byte {
+	return []byte(fmt.Sprintf("mute/%s", strings.ToLower(address)))
+}
+
+func ackKey(agentID, subject string) []byte {
+	return []byte(fmt.Sprintf("ack/%s/%s", agentID, subject))
+}
+
+func minAmountKey(agentID string) []byte {
+	return []byte(fmt.Sprintf("min-amount/%s", agentID))
+}
@@ -0,0 +1,27 @@
+package badgerrepo
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/canercidam/large-tx-detector/labels"
+)
+
+// SaveLabel saves the address label.
+func (repo *Repository) SaveLabel(label *labels.Label) error {
+	return repo.set(labelKey(label.Address), label, 0)
+}
+
+// GetLabel gets the label of the address.
+func (repo *Repository) GetLabel(address string) (*labels.Label, error) {
+	var label labels.Label
+	found, err := repo.get(labelKey(address), &label)
+	if !found || err != nil {
+		return nil, err
+	}
+	return &label, nil
+}
+
+func labelKey(address string) []byte {
+	return []byte(fmt.Sprintf("label/%s", strings.ToLower(address)))
+}
@@ -1,6 +1,9 @@
 package badgerrepo
 
 import (
+	"encoding/json"
+	"time"
+
 	badger "github.com/dgraph-io/badger/v3"
 )
 
@@ -28,3 +31,38 @@ func New(path string) (*Repository, error) {
 func (repo *Repository) Close() error {
 	return repo.db.Close()
 }
+
+// set saves the value as JSON. The entry does not expire if the TTL is zero.
+func (repo *Repository) set(key []byte, v interface{}, ttl time.Duration) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return repo.db.Update(func(txn *badger.Txn) error {
+		entry := badger.NewEntry(key, b)
+		if ttl > 0 {
+			entry = entry.WithTTL(ttl)
+		}
+		return txn.SetEntry(entry)
+	})
+}
+
+// get reads the JSON value and tells if it was found.
+func (repo *Repository) get(key []byte, v interface{}) (bool, error) {
+	err := repo.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(key)
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			return json.Unmarshal(val, v)
+		})
+	})
+	if err == badger.ErrKeyNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
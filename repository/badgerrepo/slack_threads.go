@@ -0,0 +1,27 @@
+package badgerrepo
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/canercidam/large-tx-detector/core/agent"
+)
+
+// SaveSlackThread saves the thread until it expires.
+func (repo *Repository) SaveSlackThread(thread *agent.SlackThread, ttl time.Duration) error {
+	return repo.set(slackThreadKey(thread.ChannelID, thread.Key), thread, ttl)
+}
+
+// GetSlackThread gets the saved thread.
+func (repo *Repository) GetSlackThread(channelID, key string) (*agent.SlackThread, error) {
+	var thread agent.SlackThread
+	found, err := repo.get(slackThreadKey(channelID, key), &thread)
+	if !found || err != nil {
+		return nil, err
+	}
+	return &thread, nil
+}
+
+func slackThreadKey(channelID, key string) []byte {
+	return []byte(fmt.Sprintf("slack-thread/%s/%s", channelID, key))
+}
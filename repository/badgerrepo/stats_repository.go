@@ -0,0 +1,26 @@
+package badgerrepo
+
+import (
+	"fmt"
+
+	"github.com/canercidam/large-tx-detector/agents"
+)
+
+// SaveTransferStats saves the transfer stats of the agent.
+func (repo *Repository) SaveTransferStats(agentID string, stats *agents.TransferStats) error {
+	return repo.set(transferStatsKey(agentID), stats, 0)
+}
+
+// GetTransferStats gets the transfer stats of the agent.
+func (repo *Repository) GetTransferStats(agentID string) (*agents.TransferStats, error) {
+	var stats agents.TransferStats
+	found, err := repo.get(transferStatsKey(agentID), &stats)
+	if !found || err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+func transferStatsKey(agentID string) []byte {
+	return []byte(fmt.Sprintf("transfer-stats/%s", agentID))
+}
@@ -0,0 +1,26 @@
+package badgerrepo
+
+import (
+	"fmt"
+
+	"github.com/canercidam/large-tx-detector/agents"
+)
+
+// SaveSupplySnapshot saves the supply snapshot of the agent.
+func (repo *Repository) SaveSupplySnapshot(agentID string, snapshot *agents.SupplySnapshot) error {
+	return repo.set(supplySnapshotKey(agentID), snapshot, 0)
+}
+
+// GetSupplySnapshot gets the supply snapshot of the agent.
+func (repo *Repository) GetSupplySnapshot(agentID string) (*agents.SupplySnapshot, error) {
+	var snapshot agents.SupplySnapshot
+	found, err := repo.get(supplySnapshotKey(agentID), &snapshot)
+	if !found || err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+func supplySnapshotKey(agentID string) []byte {
+	return []byte(fmt.Sprintf("supply-snapshot/%s", agentID))
+}
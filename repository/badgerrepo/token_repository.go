@@ -0,0 +1,27 @@
+package badgerrepo
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/canercidam/large-tx-detector/contracts"
+)
+
+// SaveTokenMetadata saves the token metadata.
+func (repo *Repository) SaveTokenMetadata(token *contracts.TokenMetadata) error {
+	return repo.set(tokenKey(token.Address), token, 0)
+}
+
+// GetTokenMetadata gets the saved token metadata.
+func (repo *Repository) GetTokenMetadata(address string) (*contracts.TokenMetadata, error) {
+	var token contracts.TokenMetadata
+	found, err := repo.get(tokenKey(address), &token)
+	if !found || err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func tokenKey(address string) []byte {
+	return []byte(fmt.Sprintf("token/%s", strings.ToLower(address)))
+}
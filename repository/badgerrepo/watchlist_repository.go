@@ -0,0 +1,57 @@
+package badgerrepo
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/canercidam/large-tx-detector/agents"
+	badger "github.com/dgraph-io/badger/v3"
+)
+
+const watchlistPrefix = "watchlist/"
+
+// SaveWatchlistEntry saves the watchlist entry.
+func (repo *Repository) SaveWatchlistEntry(entry *agents.WatchlistEntry) error {
+	return repo.set(watchlistKey(entry.Address, entry.Watchlist), entry, 0)
+}
+
+// DeleteWatchlistEntry deletes the address from the watchlist.
+func (repo *Repository) DeleteWatchlistEntry(watchlist, address string) error {
+	return repo.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete(watchlistKey(address, watchlist))
+	})
+}
+
+// GetWatchlistEntries gets the entries of the address in all watchlists.
+func (repo *Repository) GetWatchlistEntries(address string) ([]*agents.WatchlistEntry, error) {
+	return repo.listWatchlistEntries(fmt.Sprintf("%s%s/", watchlistPrefix, strings.ToLower(address)))
+}
+
+// ListWatchlistEntries gets all watchlist entries.
+func (repo *Repository) ListWatchlistEntries() ([]*agents.WatchlistEntry, error) {
+	return repo.listWatchlistEntries(watchlistPrefix)
+}
+
+func (repo *Repository) listWatchlistEntries(prefix string) ([]*agents.WatchlistEntry, error) {
+	var entries []*agents.WatchlistEntry
+	err := repo.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+		for it.Seek([]byte(prefix)); it.ValidForPrefix([]byte(prefix)); it.Next() {
+			var entry agents.WatchlistEntry
+			if err := it.Item().Value(func(val []byte) error {
+				return json.Unmarshal(val, &entry)
+			}); err != nil {
+				return err
+			}
+			entries = append(entries, &entry)
+		}
+		return nil
+	})
+	return entries, err
+}
+
+func watchlistKey(address, watchlist string) []byte {
+	return []byte(fmt.Sprintf("%s%s/%s", watchlistPrefix, strings.ToLower(address), watchlist))
+}
@@ -0,0 +1,27 @@
+package badgerrepo
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/canercidam/large-tx-detector/agents"
+)
+
+// SaveTransferWindow saves the aggregation window until it expires.
+func (repo *Repository) SaveTransferWindow(agentID string, window *agents.TransferWindow, ttl time.Duration) error {
+	return repo.set(transferWindowKey(agentID, window.Key), window, ttl)
+}
+
+// GetTransferWindow gets the aggregation window.
+func (repo *Repository) GetTransferWindow(agentID, key string) (*agents.TransferWindow, error) {
+	var window agents.TransferWindow
+	found, err := repo.get(transferWindowKey(agentID, key), &window)
+	if !found || err != nil {
+		return nil, err
+	}
+	return &window, nil
+}
+
+func transferWindowKey(agentID, key string) []byte {
+	return []byte(fmt.Sprintf("transfer-window/%s/%s", agentID, key))
+}
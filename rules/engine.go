@@ -0,0 +1,105 @@
+package rules
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/antonmedv/expr"
+	"github.com/antonmedv/expr/vm"
+	"github.com/canercidam/large-tx-detector/core/agent"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// AnyAgent is the agent ID of the rules which apply to all agents.
+const AnyAgent = "*"
+
+// Rule is a boolean expression which the findings must satisfy to be notified about.
+type Rule struct {
+	Name       string `json:"name"`
+	Expression string `json:"expression"`
+
+	program *vm.Program
+}
+
+// Engine filters the findings with the rules of the agents.
+type Engine struct {
+	state StateReader
+	rules map[string][]*Rule
+}
+
+// Load reads the rules by agent ID from a JSON file and compiles them.
+func Load(path string, state StateReader) (*Engine, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rules map[string][]*Rule
+	if err := json.Unmarshal(b, &rules); err != nil {
+		return nil, fmt.Errorf("failed to decode the rules: %v", err)
+	}
+	return NewEngine(rules, state)
+}
+
+// NewEngine compiles the rules by agent ID.
+func NewEngine(rules map[string][]*Rule, state StateReader) (*Engine, error) {
+	for agentID, agentRules := range rules {
+		for i, rule := range agentRules {
+			if len(rule.Name) == 0 {
+				rule.Name = fmt.Sprintf("%s#%d", agentID, i)
+			}
+			program, err := Compile(rule.Expression)
+			if err != nil {
+				return nil, fmt.Errorf("invalid rule '%s': %v", rule.Name, err)
+			}
+			rule.program = program
+		}
+	}
+	return &Engine{state: state, rules: rules}, nil
+}
+
+// Compile checks and compiles a boolean expression.
+func Compile(expression string) (*vm.Program, error) {
+	if len(expression) == 0 {
+		return nil, errors.New("empty expression")
+	}
+	prototype := newEnv(context.Background(), nil, &agent.Finding{}, nil)
+	return expr.Compile(expression, expr.Env(prototype), expr.AsBool())
+}
+
+// Evaluate runs the compiled expression against the finding and the transaction.
+func Evaluate(ctx context.Context, program *vm.Program, state StateReader, finding *agent.Finding, tx *types.Transaction) (bool, error) {
+	return run(program, newEnv(ctx, state, finding, tx))
+}
+
+func run(program *vm.Program, env map[string]interface{}) (bool, error) {
+	out, err := expr.Run(program, env)
+	if err != nil {
+		return false, err
+	}
+	return out.(bool), nil
+}
+
+// Filter returns the first rule of the agent which the finding does not satisfy, or nil
+// if the finding should be notified about.
+func (e *Engine) Filter(ctx context.Context, finding *agent.Finding, tx *types.Transaction) (*Rule, error) {
+	if e == nil {
+		return nil, nil
+	}
+	// The env is shared by the rules so that the lazy values are read once.
+	env := newEnv(ctx, e.state, finding, tx)
+	for _, agentID := range []string{AnyAgent, finding.AgentID} {
+		for _, rule := range e.rules[agentID] {
+			ok, err := run(rule.program, env)
+			if err != nil {
+				return nil, fmt.Errorf("failed to evaluate rule '%s': %v", rule.Name, err)
+			}
+			if !ok {
+				return rule, nil
+			}
+		}
+	}
+	return nil, nil
+}
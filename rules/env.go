@@ -0,0 +1,140 @@
+package rules
+
+import (
+	"context"
+	"math/big"
+	"strconv"
+
+	"github.com/canercidam/large-tx-detector/core/agent"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// StateReader reads the account state at a block.
+type StateReader interface {
+	NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+	CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error)
+}
+
+// Account is a sender or a receiver in the expressions. The state is read
+// lazily when the expression calls the methods.
+type Account struct {
+	Address  string
+	Label    string
+	Category string
+
+	ctx    context.Context
+	state  StateReader
+	block  *big.Int
+	nonce  *uint64
+	isCont *bool
+}
+
+// Nonce returns the nonce of the account at the block.
+func (acc *Account) Nonce() (uint64, error) {
+	if acc.nonce != nil {
+		return *acc.nonce, nil
+	}
+	if acc.state == nil {
+		return 0, nil
+	}
+	nonce, err := acc.state.NonceAt(acc.ctx, common.HexToAddress(acc.Address), acc.block)
+	if err != nil {
+		return 0, err
+	}
+	acc.nonce = &nonce
+	return nonce, nil
+}
+
+// IsContract tells if the account has code at the block.
+func (acc *Account) IsContract() (bool, error) {
+	if acc.isCont != nil {
+		return *acc.isCont, nil
+	}
+	if acc.state == nil {
+		return false, nil
+	}
+	code, err := acc.state.CodeAt(acc.ctx, common.HexToAddress(acc.Address), acc.block)
+	if err != nil {
+		return false, err
+	}
+	isContract := len(code) > 0
+	acc.isCont = &isContract
+	return isContract, nil
+}
+
+// Tx is the transaction data in the expressions.
+type Tx struct {
+	Hash string
+	To   string
+	// Value is in ETH and GasPrice is in gwei.
+	Value    float64
+	GasPrice float64
+	Gas      uint64
+	Nonce    uint64
+	// Method is the hex function selector of the call, if any.
+	Method string
+}
+
+// newEnv creates the expression variables from the finding and the transaction.
+// The state is used for the lazy account values and the tx can be nil.
+func newEnv(ctx context.Context, state StateReader, finding *agent.Finding, tx *types.Transaction) map[string]interface{} {
+	block := big.NewInt(0).SetUint64(finding.BlockNumber)
+	account := func(address, label, category string) *Account {
+		return &Account{
+			Address:  finding.Metadata[address],
+			Label:    finding.Metadata[label],
+			Category: finding.Metadata[category],
+			ctx:      ctx,
+			state:    state,
+			block:    block,
+		}
+	}
+	return map[string]interface{}{
+		"agentId":     finding.AgentID,
+		"alertType":   finding.AlertType,
+		"severity":    string(finding.Severity),
+		"blockNumber": int(finding.BlockNumber),
+		"txHash":      finding.TxHash,
+		"token":       finding.Metadata[agent.MetadataToken],
+		"symbol":      finding.Metadata[agent.MetadataSymbol],
+		"watchlist":   finding.Metadata[agent.MetadataWatchlist],
+		"amount":      parseFloat(finding.Metadata[agent.MetadataAmount]),
+		"amountUsd":   parseFloat(finding.Metadata[agent.MetadataAmountUSD]),
+		"from":        account(agent.MetadataFrom, agent.MetadataFromLabel, agent.MetadataFromCategory),
+		"to":          account(agent.MetadataTo, agent.MetadataToLabel, agent.MetadataToCategory),
+		"tx":          newTx(tx),
+	}
+}
+
+func newTx(tx *types.Transaction) *Tx {
+	if tx == nil {
+		return &Tx{}
+	}
+	t := &Tx{
+		Hash:     tx.Hash().Hex(),
+		Value:    toFloat(tx.Value(), 18),
+		GasPrice: toFloat(tx.GasPrice(), 9),
+		Gas:      tx.Gas(),
+		Nonce:    tx.Nonce(),
+	}
+	if tx.To() != nil {
+		t.To = tx.To().Hex()
+	}
+	if data := tx.Data(); len(data) >= 4 {
+		t.Method = common.Bytes2Hex(data[:4])
+	}
+	return t
+}
+
+func toFloat(value *big.Int, decimals int64) float64 {
+	exp := big.NewInt(0).Exp(big.NewInt(10), big.NewInt(decimals), nil)
+	f, _ := big.NewRat(0, 1).SetFrac(value, exp).Float64()
+	return f
+}
+
+// parseFloat parses the decimal metadata values. Missing values are zero.
+func parseFloat(s string) float64 {
+	f, _ := strconv.ParseFloat(s, 64)
+	return f
+}
@@ -0,0 +1,80 @@
+package rules
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/canercidam/large-tx-detector/core/agent"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Client reads the chain data for testing the expressions.
+type Client interface {
+	StateReader
+	TransactionByHash(ctx context.Context, hash common.Hash) (tx *types.Transaction, isPending bool, err error)
+}
+
+// TestRequest is an expression to evaluate against a sample finding.
+type TestRequest struct {
+	Expression string         `json:"expression"`
+	Finding    *agent.Finding `json:"finding"`
+}
+
+// TestResponse is the result of a test request.
+type TestResponse struct {
+	Result bool   `json:"result"`
+	Error  string `json:"error,omitempty"`
+}
+
+// TestHandler evaluates the expressions in the POST requests so that the rules can be
+// tried out before they are configured. The transaction of the finding is read from the chain.
+type TestHandler struct {
+	client Client
+}
+
+// NewTestHandler creates a new rule test handler.
+func NewTestHandler(client Client) *TestHandler {
+	return &TestHandler{client: client}
+}
+
+// ServeHTTP implements http.Handler.
+func (th *TestHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var req TestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+	if req.Finding == nil {
+		req.Finding = &agent.Finding{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	result, err := th.evaluate(r.Context(), &req)
+	if err != nil {
+		json.NewEncoder(w).Encode(&TestResponse{Error: err.Error()})
+		return
+	}
+	json.NewEncoder(w).Encode(&TestResponse{Result: result})
+}
+
+func (th *TestHandler) evaluate(ctx context.Context, req *TestRequest) (bool, error) {
+	program, err := Compile(req.Expression)
+	if err != nil {
+		return false, err
+	}
+	var tx *types.Transaction
+	if len(req.Finding.TxHash) > 0 {
+		tx, _, err = th.client.TransactionByHash(ctx, common.HexToHash(req.Finding.TxHash))
+		if err != nil {
+			return false, fmt.Errorf("failed to get the transaction: %v", err)
+		}
+	}
+	return Evaluate(ctx, program, th.client, req.Finding, tx)
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Config vars
+var (
+	ShutdownTimeout = time.Second * 5
+)
+
+// Server serves the HTTP endpoints of the service.
+type Server struct {
+	mux *http.ServeMux
+	srv *http.Server
+}
+
+// New creates a new server.
+func New(addr string) *Server {
+	mux := http.NewServeMux()
+	return &Server{
+		mux: mux,
+		srv: &http.Server{
+			Addr:         addr,
+			Handler:      mux,
+			ReadTimeout:  time.Second * 10,
+			WriteTimeout: time.Second * 10,
+		},
+	}
+}
+
+// Handle registers the handler for the path.
+func (s *Server) Handle(path string, handler http.Handler) {
+	s.mux.Handle(path, handler)
+}
+
+// Start starts serving in the background until the context is done.
+func (s *Server) Start(ctx context.Context) {
+	go func() {
+		if err := s.srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Printf("http server failed: %v", err)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+		defer cancel()
+		s.srv.Shutdown(shutdownCtx)
+	}()
+}
+
+// RequireToken requires the bearer token for the handler. All requests are rejected if the token is empty.
+func RequireToken(token string, handler http.Handler) http.Handler {
+	if len(token) == 0 {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+		})
+	}
+	expected := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}